@@ -2,16 +2,12 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var devAccounts [][]string = [][]string{
-	{"supafiya", "devduncan89@gmail.com", "123"},
-	{"nyro", "nyronic@gmail.com", "123"},
-}
-
 type Account struct {
 	ID       primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
 	Username string             `json:"username" bson:"username"`
@@ -29,9 +25,9 @@ type Account struct {
 
 // Create a new account with provided Username, Email, Role and Status
 func NewAccount(u string, e string, r AccountRole, st AccountStatus) *Account {
-	ts := time.Now().UTC()
+	ts := Now()
 	return &Account{
-		ID:        primitive.NewObjectID(),
+		ID:        NewObjectID(),
 		Status:    st,
 		Username:  u,
 		Email:     e,
@@ -43,20 +39,33 @@ func NewAccount(u string, e string, r AccountRole, st AccountStatus) *Account {
 
 // Generate Accounts
 func (s *MongoStore) GenerateAccounts(min, max int) {
-	s.GenerateDevAccounts()
+	// dev accounts already exist when appending
+	if !s.Config.Append {
+		s.GenerateDevAccounts()
+	}
 	accountCount := RandomIntBetween(min, max)
+	accounts := make([]*Account, 0, accountCount)
+	plaintexts := make([]string, 0, accountCount)
+
 	for i := 0; i < accountCount; i++ {
 		fmt.Print("\033[G\033[K")
-		fmt.Printf(" - Generating Accounts & Sessions (expensive hashes are slow): %v/%v", i+1, accountCount)
+		fmt.Printf(" - Generating Accounts & Sessions: %v/%v", i+1, accountCount)
+
+		username := s.unique.Unique(UniqueUsername, GetUsername)
+		email := s.unique.Unique(UniqueEmail, GetEmail)
+		account := NewAccount(username, email, GetWeightedRole(), GetWeightedAccountStatus())
 
-		ts := time.Now().UTC()
-		pw, _ := HashPassword("123")
+		joined := s.timeline.After(s.timeline.Start)
+		account.CreatedAt = timePtr(joined)
+		account.UpdatedAt = timePtr(s.timeline.After(joined))
 
-		account := NewAccount(GetUsername(), GetEmail(), GetWeightedRole(), GetWeightedAccountStatus())
-		account.Password = pw
-		account.UpdatedAt = &ts
+		plaintext := s.NewPassword()
+		accounts = append(accounts, account)
+		plaintexts = append(plaintexts, plaintext)
 
 		session := NewSessionFromAccount(account)
+		s.placeSession(session, account)
+		s.addCredential(account, plaintext, session)
 
 		s.cSessions = append(s.cSessions, session)
 		s.cAccounts = append(s.cAccounts, account)
@@ -69,20 +78,62 @@ func (s *MongoStore) GenerateAccounts(min, max int) {
 	}
 
 	fmt.Print("\n")
+	s.unique.Print()
+
+	// hashed all at once, in parallel
+	if err := s.hashAccountPasswords(accounts, plaintexts); err != nil {
+		log.Fatal(err)
+	}
 }
 
-// Generate Dev Accounts
+// Generate the dev accounts of the config
 func (s *MongoStore) GenerateDevAccounts() {
-	for i := 0; i < len(devAccounts); i++ {
+	devAccounts := s.Config.DevAccounts
+	if len(devAccounts) == 0 {
+		return
+	}
+
+	accounts := []*Account{}
+	plaintexts := []string{}
+
+	for i, dev := range devAccounts {
 		fmt.Print("\033[G\033[K")
 		fmt.Printf(" - Generating Dev Accounts: %v/%v", i+1, len(devAccounts))
-		account := NewAccount(devAccounts[i][0], devAccounts[i][1], AccountRoleAdmin, AccountStatusActive)
-		pwh, _ := HashPassword(devAccounts[i][2])
-		account.Password = pwh
+
+		role, status := dev.Role, dev.Status
+		if role == "" {
+			role = AccountRoleAdmin
+		}
+		if status == "" {
+			status = AccountStatusActive
+		}
+
+		// random accounts can't take these
+		if !s.unique.Reserve(UniqueUsername, dev.Username) || !s.unique.Reserve(UniqueEmail, dev.Email) {
+			log.Fatalf("dev account %s: username or email already taken", dev.Username)
+		}
+
+		// dev accounts are there from the very start
+		account := NewAccount(dev.Username, dev.Email, role, status)
+		account.CreatedAt = timePtr(s.timeline.Start)
+		account.UpdatedAt = timePtr(s.timeline.Start)
+
+		accounts = append(accounts, account)
+		plaintexts = append(plaintexts, dev.Password)
+		s.addCredential(account, dev.Password, nil)
 
 		s.cAccounts = append(s.cAccounts, account)
+		if role == AccountRoleAdmin {
+			s.cAdmins = append(s.cAdmins, &account.ID)
+		} else if role == AccountRoleMod {
+			s.cMods = append(s.cMods, &account.ID)
+		}
 	}
 	fmt.Print("\n")
+
+	if err := s.hashAccountPasswords(accounts, plaintexts); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // Get Random Admin ID
@@ -106,6 +157,16 @@ func (s *MongoStore) GetRandomAccountID() primitive.ObjectID {
 	return s.cAccounts[RandomIntBetween(0, len(s.cAccounts))].ID
 }
 
+// account with the id, nil if there's none
+func (s *MongoStore) GetAccountByID(id primitive.ObjectID) *Account {
+	for _, account := range s.cAccounts {
+		if account.ID == id {
+			return account
+		}
+	}
+	return nil
+}
+
 // Get random account
 func (s *MongoStore) GetRandomAccount() *Account {
 	return s.cAccounts[RandomIntBetween(0, len(s.cAccounts))]
@@ -120,32 +181,17 @@ func (s *MongoStore) GetRandomModAdminIDList() map[int]primitive.ObjectID {
 
 	idMap[0] = *s.cAdmins[adminIndex]
 
-	for i := 1; i < aditionalAdminCt; i++ {
-		var adminId primitive.ObjectID
-
-		if adminIndex+i >= len(s.cAdmins) {
-			adminId = *s.cAdmins[adminIndex-i]
-		} else {
-			adminId = *s.cAdmins[adminIndex+i]
-		}
-
-		idMap[len(idMap)] = adminId
+	// wrap around so small admin/mod pools never index out of range or repeat
+	for i := 1; i < aditionalAdminCt && i < len(s.cAdmins); i++ {
+		idMap[len(idMap)] = *s.cAdmins[(adminIndex+i)%len(s.cAdmins)]
 	}
 
-	if RandomIntBetween(0, 100) > 70 {
+	if len(s.cMods) > 0 && RandomIntBetween(0, 100) > 70 {
 		modIndex := RandomIntBetween(0, len(s.cMods))
 		idMap[len(idMap)] = *s.cMods[modIndex]
 
-		if RandomIntBetween(0, 100) > 80 {
-			var modId primitive.ObjectID
-
-			if modIndex+1 >= len(s.cMods) {
-				modId = *s.cMods[modIndex-1]
-			} else {
-				modId = *s.cMods[modIndex+1]
-			}
-
-			idMap[len(idMap)] = modId
+		if len(s.cMods) > 1 && RandomIntBetween(0, 100) > 80 {
+			idMap[len(idMap)] = *s.cMods[(modIndex+1)%len(s.cMods)]
 		}
 	}
 
@@ -0,0 +1,65 @@
+package main
+
+import "fmt"
+
+// an upload asked for more distinct sources than the pool holds
+type AssetExhaustedError struct {
+	Requested int
+	Available int
+}
+
+func (e *AssetExhaustedError) Error() string {
+	return fmt.Sprintf("asset sources exhausted: %d requested, %d available", e.Requested, e.Available)
+}
+
+// picks the asset sources of one upload without replacement
+type AssetAllocator struct {
+	Pool int        // sources to pick from, indexes 0 to Pool-1
+	Draw func() int // preferred index, uniform when nil. Out of range and taken indexes are redrawn
+}
+
+// n distinct indexes of the pool. Draw gets a few tries per index, what it didn't come up with
+// is taken in order from a random index on so a skewed or tiny pool always fills the request.
+// Asking for more than the pool holds returns every index and an *AssetExhaustedError
+func (a AssetAllocator) Allocate(n int) ([]int, error) {
+	picked := []int{}
+	if n <= 0 {
+		return picked, nil
+	}
+
+	want := n
+	if want > a.Pool {
+		want = a.Pool
+	}
+
+	draw := a.Draw
+	if draw == nil {
+		draw = func() int { return RandomIntBetween(0, a.Pool) }
+	}
+
+	taken := make(map[int]bool, want)
+	for tries := 0; len(picked) < want && tries < want*4; tries++ {
+		index := draw()
+		if index < 0 || index >= a.Pool || taken[index] {
+			continue
+		}
+		taken[index] = true
+		picked = append(picked, index)
+	}
+
+	if len(picked) < want {
+		start := RandomIntBetween(0, a.Pool)
+		for i := 0; len(picked) < want; i++ {
+			index := (start + i) % a.Pool
+			if !taken[index] {
+				taken[index] = true
+				picked = append(picked, index)
+			}
+		}
+	}
+
+	if n > a.Pool {
+		return picked, &AssetExhaustedError{Requested: n, Available: a.Pool}
+	}
+	return picked, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestAssetAllocatorAllocate(t *testing.T) {
+	cases := []struct {
+		name      string
+		pool      int
+		n         int
+		draw      func() int
+		want      int
+		exhausted *AssetExhaustedError
+	}{
+		{name: "empty pool", pool: 0, n: 3, want: 0, exhausted: &AssetExhaustedError{Requested: 3, Available: 0}},
+		{name: "one of one", pool: 1, n: 1, want: 1},
+		{name: "more than one", pool: 1, n: 4, want: 1, exhausted: &AssetExhaustedError{Requested: 4, Available: 1}},
+		{name: "whole pool", pool: 10, n: 10, want: 10},
+		{name: "part of the pool", pool: 10, n: 3, want: 3},
+		{name: "more than the pool", pool: 10, n: 25, want: 10, exhausted: &AssetExhaustedError{Requested: 25, Available: 10}},
+		{name: "none", pool: 10, n: 0, want: 0},
+		{name: "negative", pool: 10, n: -2, want: 0},
+		{name: "always the top source", pool: 10, n: 6, draw: func() int { return 0 }, want: 6},
+		{name: "out of range draws", pool: 5, n: 5, draw: func() int { return 7 }, want: 5},
+		{name: "negative draws", pool: 5, n: 2, draw: func() int { return -1 }, want: 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rng = rand.New(rand.NewSource(1))
+			picked, err := AssetAllocator{Pool: c.pool, Draw: c.draw}.Allocate(c.n)
+
+			if len(picked) != c.want {
+				t.Fatalf("got %d indexes, want %d", len(picked), c.want)
+			}
+			seen := map[int]bool{}
+			for _, index := range picked {
+				if index < 0 || index >= c.pool {
+					t.Errorf("index %d out of the pool of %d", index, c.pool)
+				}
+				if seen[index] {
+					t.Errorf("index %d picked twice", index)
+				}
+				seen[index] = true
+			}
+
+			if c.exhausted == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var exhausted *AssetExhaustedError
+			if !errors.As(err, &exhausted) {
+				t.Fatalf("got error %v, want an *AssetExhaustedError", err)
+			}
+			if *exhausted != *c.exhausted {
+				t.Errorf("got %+v, want %+v", *exhausted, *c.exhausted)
+			}
+		})
+	}
+}
+
+// a skewed draw still comes first, the allocator only fills in what it couldn't draw
+func TestAssetAllocatorKeepsDraws(t *testing.T) {
+	rng = rand.New(rand.NewSource(1))
+	draws := []int{3, 3, 3, 1}
+	i := 0
+	picked, err := AssetAllocator{Pool: 8, Draw: func() int {
+		d := draws[i%len(draws)]
+		i++
+		return d
+	}}.Allocate(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(picked) != 2 || picked[0] != 3 || picked[1] != 1 {
+		t.Errorf("got %v, want [3 1]", picked)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// collections an append run loads, everything new documents may reference
+var appendCollections = []string{"boards", "accounts", "threads", "identities", "asset_sources"}
+
+// sinks that can overwrite documents already written, loaded documents that
+// gained references (board threads, thread posts, uploaders...) are replaced
+type Replacer interface {
+	ReplaceBatch(ctx context.Context, colName string, docs []interface{}) error
+}
+
+// loads the existing documents into the store caches so generated documents reference them,
+// post numbers continue from the stored board counters
+func (s *MongoStore) LoadExisting(ctx context.Context, loader CollectionLoader) error {
+	hrPrint("Append - Loading Existing Documents")
+
+	d := &Dataset{}
+	targets := d.targets()
+
+	for _, name := range appendCollections {
+		fmt.Print("\033[G\033[K")
+		fmt.Printf(" - Loading %s", name)
+		if err := loader.LoadCollection(ctx, name, targets[name]); err != nil {
+			return fmt.Errorf("loading %s: %w", name, err)
+		}
+	}
+	fmt.Print("\n")
+
+	s.loaded = make(map[primitive.ObjectID][sha256.Size]byte)
+
+	for _, board := range d.Boards {
+		s.PostRefs[board.Short] = board.PostRef
+		s.cBoards = append(s.cBoards, board)
+		s.remember(board.ID, board)
+	}
+
+	for _, account := range d.Accounts {
+		s.cAccounts = append(s.cAccounts, account)
+		s.remember(account.ID, account)
+		s.unique.Reserve(UniqueUsername, account.Username)
+		s.unique.Reserve(UniqueEmail, account.Email)
+
+		if account.Role == AccountRoleAdmin {
+			s.cAdmins = append(s.cAdmins, &account.ID)
+		} else if account.Role == AccountRoleMod {
+			s.cMods = append(s.cMods, &account.ID)
+		}
+	}
+
+	for _, thread := range d.Threads {
+		s.cThreads = append(s.cThreads, thread)
+		s.cUserThreadIdentitys[thread.ID] = make(map[primitive.ObjectID]*Identity)
+		s.remember(thread.ID, thread)
+	}
+
+	// anonymized identities have no account and are never handed out again
+	for _, identity := range d.Identities {
+		s.cIdentites = append(s.cIdentites, identity)
+		s.remember(identity.ID, identity)
+
+		if byAccount, ok := s.cUserThreadIdentitys[identity.Thread]; ok && !identity.Account.IsZero() {
+			byAccount[identity.Account] = identity
+		}
+	}
+
+	for i, src := range d.AssetSources {
+		s.cAssetSrcMap[i] = src
+		s.remember(src.ID, src)
+	}
+
+	fmt.Printf(" - Loaded %d boards, %d accounts, %d threads, %d identities and %d asset sources\n",
+		len(d.Boards), len(d.Accounts), len(d.Threads), len(d.Identities), len(d.AssetSources))
+
+	// the loaded documents may come from a run with the same seed
+	MixSeed(int64(len(s.loaded)))
+
+	return s.checkAppendable()
+}
+
+// the enabled generators need something to reference
+func (s *MongoStore) checkAppendable() error {
+	c := s.Config
+
+	if (c.Threads.Enabled || c.Posts.Enabled) && (len(s.cBoards) == 0 && !c.Boards.Enabled || len(s.cAccounts) == 0 && !c.Accounts.Enabled) {
+		return errors.New("appending threads or posts needs existing or generated boards and accounts")
+	}
+	if c.Posts.Enabled && len(s.cThreads) == 0 && !c.Threads.Enabled {
+		return errors.New("appending posts needs existing or generated threads")
+	}
+	if c.Articles.Enabled && len(s.cAdmins) == 0 && !c.Accounts.Enabled {
+		return errors.New("appending articles needs an existing admin account")
+	}
+
+	return nil
+}
+
+// fingerprint of a loaded document, to tell later whether generating modified it
+func (s *MongoStore) remember(id primitive.ObjectID, doc interface{}) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return
+	}
+	s.loaded[id] = sha256.Sum256(raw)
+}
+
+// splits documents into new ones and loaded ones that changed since loading,
+// loaded documents that didn't change are dropped
+func (s *MongoStore) splitLoaded(docs []interface{}) (fresh, changed []interface{}) {
+	for _, doc := range docs {
+		sum, ok := s.loaded[docID(doc)]
+		if !ok {
+			fresh = append(fresh, doc)
+			continue
+		}
+
+		raw, err := bson.Marshal(doc)
+		if err != nil || sha256.Sum256(raw) != sum {
+			changed = append(changed, doc)
+		}
+	}
+	return fresh, changed
+}
+
+// replaces documents through the sink, if it supports it
+func (s *MongoStore) replaceBatch(ctx context.Context, colName string, docs []interface{}) error {
+	replacer, ok := s.Sink.(Replacer)
+	if !ok {
+		return fmt.Errorf("%T can't replace existing documents", s.Sink)
+	}
+	return replacer.ReplaceBatch(ctx, colName, docs)
+}
+
+// _id of a model, every model keeps it in an ID field
+func docID(doc interface{}) primitive.ObjectID {
+	v := reflect.Indirect(reflect.ValueOf(doc))
+	if v.Kind() != reflect.Struct {
+		return primitive.NilObjectID
+	}
+	id, _ := v.FieldByName("ID").Interface().(primitive.ObjectID)
+	return id
+}
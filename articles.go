@@ -52,7 +52,7 @@ type ArticleAuthor struct {
 // new article author
 func NewArticleAuthor(author primitive.ObjectID, anonimize bool) *ArticleAuthor {
 	return &ArticleAuthor{
-		ID:        primitive.NewObjectID(),
+		ID:        NewObjectID(),
 		AuthorID:  author,
 		Anonymize: anonimize,
 	}
@@ -60,9 +60,9 @@ func NewArticleAuthor(author primitive.ObjectID, anonimize bool) *ArticleAuthor
 
 // new article comment
 func NewArticleComment() *ArticleComment {
-	ts := time.Now().UTC()
+	ts := Now()
 	return &ArticleComment{
-		ID:            primitive.NewObjectID(),
+		ID:            NewObjectID(),
 		AuthorID:      primitive.NilObjectID,
 		AuthorAnon:    false,
 		CommentNumber: 0,
@@ -75,16 +75,16 @@ func NewArticleComment() *ArticleComment {
 
 // new article
 func NewArticle() *Article {
-	ts := time.Now().UTC()
+	ts := Now()
 	return &Article{
-		ID:         primitive.NewObjectID(),
+		ID:         NewObjectID(),
 		AuthorID:   primitive.NilObjectID,
 		CoAuthors:  []primitive.ObjectID{},
 		Status:     GetWeightedArticleStatus(),
 		CommentRef: 0,
 		Comments:   []primitive.ObjectID{},
 		Assets:     []primitive.ObjectID{},
-		Title:      GetSentence(),
+		Title:      Unicodify(UnicodeTitles, GetSentence()),
 		Body:       GetParagraphsBetween(3, 10),
 		Slug:       GetSlug(8, 16),
 		Tags:       GetRandomTags(),
@@ -95,6 +95,11 @@ func NewArticle() *Article {
 
 // Generate Articles
 func (s *MongoStore) GenerateArticles(min, max int) {
+	if len(s.cAdmins) == 0 {
+		fmt.Println(" - Skipped Articles, no admin account to author them")
+		return
+	}
+
 	articleCount := RandomIntBetween(min, max)
 	for i := 0; i < articleCount; i++ {
 		fmt.Print("\033[G\033[K")
@@ -103,8 +108,16 @@ func (s *MongoStore) GenerateArticles(min, max int) {
 		article := NewArticle()
 		articleAuthors := s.GetRandomModAdminIDList()
 
-		for k, v := range articleAuthors {
-			aa := NewArticleAuthor(v, RandomIntBetween(0, 100) > 90)
+		// written once every author had joined
+		var authorsJoined time.Time
+
+		// walk the map by key so seeded runs draw from the rng in a stable order
+		for k := 0; k < len(articleAuthors); k++ {
+			if author := s.GetAccountByID(articleAuthors[k]); author != nil {
+				authorsJoined = latest(authorsJoined, *author.CreatedAt)
+			}
+
+			aa := NewArticleAuthor(articleAuthors[k], RandomIntBetween(0, 100) > 90)
 			if k == 0 {
 				article.AuthorID = aa.ID
 			} else {
@@ -113,12 +126,32 @@ func (s *MongoStore) GenerateArticles(min, max int) {
 			s.cArticleAuthors = append(s.cArticleAuthors, aa)
 		}
 
-		commentCount := RandomIntBetween(0, 100)
+		written := s.timeline.After(authorsJoined)
+		article.CreatedAt = timePtr(written)
+		article.UpdatedAt = timePtr(written)
+
+		// some articles get revised later on
+		if RandomIntBetween(0, 100) > 80 {
+			article.UpdatedAt = timePtr(s.timeline.After(written))
+		}
 
-		for j := 0; j < commentCount; j++ {
+		commentCount := 0
+		if s.Config.ArticleComments.Enabled {
+			commentCount = RandomIntBetween(s.Config.ArticleComments.Min, s.Config.ArticleComments.Max)
+		}
+
+		commentTimes := s.timeline.Sequence(written, commentCount)
+
+		for j := 0; j < len(commentTimes); j++ {
+			commented := commentTimes[j]
+			commentAuthor := s.GetRandomAccountJoinedBefore(commented)
+			if commentAuthor == nil {
+				continue
+			}
 			comment := NewArticleComment()
-			commentAuthor := s.GetRandomAccount()
 			comment.AuthorID = commentAuthor.ID
+			comment.CreatedAt = timePtr(commented)
+			comment.UpdatedAt = timePtr(commented)
 			comment.CommentNumber = article.CommentRef + 1
 			article.CommentRef++
 
@@ -127,24 +160,24 @@ func (s *MongoStore) GenerateArticles(min, max int) {
 			}
 
 			if RandomIntBetween(0, 100) > 80 {
-				mediaCount := RandomIntBetween(0, 9)
-				mediaIds, err := s.GenerateAssetCount(mediaCount, commentAuthor.ID)
+				mediaCount := s.RandomMediaCount()
+				mediaIds, err := s.GenerateAssetCount(mediaCount, commentAuthor.ID, commented, nil)
 				if err != nil {
-					fmt.Println(err)
-					continue
+					s.reportUploads(err)
 				}
 				comment.Assets = mediaIds
 			}
+			touch(&article.UpdatedAt, commented)
 			article.Comments = append(article.Comments, comment.ID)
 			s.cArticleComments = append(s.cArticleComments, comment)
 		}
 
 		if RandomIntBetween(0, 100) > 60 {
-			mediaCount := RandomIntBetween(0, 9)
-			mediaIds, err := s.GenerateAssetCount(mediaCount, article.AuthorID)
+			mediaCount := s.RandomMediaCount()
+			// assets belong to the author's account, not the ArticleAuthor reference
+			mediaIds, err := s.GenerateAssetCount(mediaCount, articleAuthors[0], written, nil)
 			if err != nil {
-				fmt.Println(err)
-				continue
+				s.reportUploads(err)
 			}
 			article.Assets = mediaIds
 		}
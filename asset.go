@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -30,8 +32,9 @@ func (h HashMethod) String() string {
 }
 
 type AssetSourceDetails struct {
-	Avatar *FileCtx `json:"avatar" bson:"avatar"`
-	Source *FileCtx `json:"source" bson:"source"`
+	Avatar *FileCtx   `json:"avatar" bson:"avatar"` // poster frame of videos
+	Source *FileCtx   `json:"source" bson:"source"`
+	Video  *VideoMeta `json:"video,omitempty" bson:"video,omitempty"` // videos only
 }
 
 // unique asset - make references if it already exists
@@ -75,30 +78,76 @@ type FileCtx struct {
 	HashSHA256     string `json:"hash_sha256" bson:"hash_sha256"`
 }
 
+type VideoMeta struct {
+	Duration   float64 `json:"duration" bson:"duration"` // seconds
+	Container  string  `json:"container" bson:"container"`
+	VideoCodec string  `json:"video_codec" bson:"video_codec"`
+	AudioCodec string  `json:"audio_codec,omitempty" bson:"audio_codec,omitempty"` // silent videos have none
+	Bitrate    uint32  `json:"bitrate" bson:"bitrate"`                             // bits per second, audio included
+	FrameRate  float64 `json:"frame_rate" bson:"frame_rate"`
+	PosterAt   float64 `json:"poster_at" bson:"poster_at"` // seconds in of the frame the avatar shows
+}
+
 // generates asset sources to create Assets from (references)
 func (s *MongoStore) GenerateAssetSources(min, max int) {
 	assetCount := RandomIntBetween(min, max)
+	offset := len(s.cAssetSrcMap) // after any loaded sources
+	written := 0
 
 	for i := 0; i < assetCount; i++ {
 		fmt.Print("\033[G\033[K")
 		fmt.Printf(" - Generating Assets: %v/%v", i+1, assetCount)
-		assetsrc := GenerateAssetSource(i)
-		s.cAssetSrcMap[i] = assetsrc
+		assetsrc := GenerateAssetSource(offset + i)
+		if uploadConfig.Dir != "" {
+			n, err := WriteUploadFiles(assetsrc)
+			if err != nil {
+				log.Fatal(err)
+			}
+			written += n
+		}
+
+		// moved back to the first upload once it's used, see GenerateAsset
+		ts := s.timeline.After(s.timeline.Start)
+		assetsrc.CreatedAt, assetsrc.UpdatedAt = timePtr(ts), timePtr(ts)
+		s.cAssetSrcMap[offset+i] = assetsrc
 	}
 	fmt.Print("\n")
+	if uploadConfig.Dir != "" {
+		fmt.Printf(" - Wrote %s of files to %s\n", FormatByteString(written), uploadConfig.Dir)
+	}
 }
 
-// generates assets for a post or thread and returns their id's
-func (s *MongoStore) GenerateAssetCount(count int, creatorId primitive.ObjectID) ([]primitive.ObjectID, error) {
-	if count > len(s.cAssetSrcMap) {
-		return nil, fmt.Errorf("invalid asset source count %d out of bounds", count)
+// random amount of media for a thread, post or comment (none if there are no asset sources)
+func (s *MongoStore) RandomMediaCount() int {
+	m := s.Config.MediaPerPost
+	if !m.Enabled || len(s.cAssetSrcMap) == 0 {
+		return 0
 	}
+	return RandomIntBetween(m.Min, m.Max)
+}
 
+// generates assets uploaded at a time for a post or thread and returns their id's. Sources
+// of the types (any for none) are drawn by popularity or from the creator's earlier uploads,
+// never twice for one post. A pool smaller than count uploads every source once and returns
+// the assets along with an *AssetExhaustedError
+func (s *MongoStore) GenerateAssetCount(count int, creatorId primitive.ObjectID, at time.Time, types []AssetType) ([]primitive.ObjectID, error) {
 	ids := []primitive.ObjectID{}
-	indexId := RandomIntBetween(9, len(s.cAssetSrcMap)-count)
 
-	for i := 0; i < count; i++ {
-		asset, err := s.GenerateAsset((indexId+i)-1, creatorId)
+	own := s.uploadHistory().byAccount[creatorId]
+	pool := s.sourcePool(types)
+	allocator := AssetAllocator{
+		Pool: len(pool.ranks),
+		Draw: func() int { return pool.pick(own, s.Config.AssetReuse.Reuploads) },
+	}
+
+	ranks, err := allocator.Allocate(count)
+	var exhausted *AssetExhaustedError
+	if err != nil && !errors.As(err, &exhausted) {
+		return nil, err
+	}
+
+	for _, rank := range ranks {
+		asset, err := s.GenerateAsset(pool.ranks[rank], creatorId, at)
 		if err != nil {
 			fmt.Printf("Error generating asset for post: %v\n - skipping\n", err)
 			continue
@@ -106,25 +155,42 @@ func (s *MongoStore) GenerateAssetCount(count int, creatorId primitive.ObjectID)
 		ids = append(ids, asset.ID)
 	}
 
-	return ids, nil
+	return ids, err
+}
+
+// reports uploads of a thread, post or comment that failed with err. The document is kept with
+// whatever assets were made, exhausted pools are counted and reported once generation is done
+func (s *MongoStore) reportUploads(err error) {
+	var exhausted *AssetExhaustedError
+	if errors.As(err, &exhausted) {
+		s.exhaustedUploads++
+		return
+	}
+	fmt.Println(err)
 }
 
 // creates an asset from the source locaated at the index and returns a pointer to it
-func (s *MongoStore) GenerateAsset(index int, creator primitive.ObjectID) (*Asset, error) {
-	if index > len(s.cAssetSrcMap) {
+func (s *MongoStore) GenerateAsset(index int, creator primitive.ObjectID, at time.Time) (*Asset, error) {
+	assetSource, ok := s.cAssetSrcMap[index]
+	if !ok || assetSource == nil {
 		return nil, fmt.Errorf("invalid asset source index %d", index)
 	}
 
-	ts := time.Now().UTC()
+	ts := at
+
+	s.uploadHistory().add(index, assetSource, creator, at)
 
-	assetSource := s.cAssetSrcMap[index]
-	assetSource.Uploaders = append(assetSource.Uploaders, creator)
+	// a source exists from its first upload on, and was last updated by the latest
+	if assetSource.CreatedAt == nil || at.Before(*assetSource.CreatedAt) {
+		assetSource.CreatedAt = timePtr(at)
+	}
+	touch(&assetSource.UpdatedAt, at)
 
 	asset := &Asset{
-		ID:          primitive.NewObjectID(),
+		ID:          NewObjectID(),
 		SourceID:    assetSource.ID,
 		AccountID:   creator,
-		FileName:    SelectAnyWord(),
+		FileName:    Unicodify(UnicodeFileNames, SelectAnyWord()),
 		Description: GetSentence(),
 		Tags:        GetRandomTags(),
 		CreatedAt:   &ts,
@@ -148,8 +214,8 @@ func (s *MongoStore) PersistAssets() error {
 // saves asset sources to the db
 func (s *MongoStore) PersistAssetSrc() error {
 	docs := []interface{}{}
-	for _, assetSrc := range s.cAssetSrcMap {
-		docs = append(docs, assetSrc)
+	for i := 0; i < len(s.cAssetSrcMap); i++ {
+		docs = append(docs, s.cAssetSrcMap[i])
 	}
 	return s.PersistDocuments(docs, "asset_sources")
 }
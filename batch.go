@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// a failure worth retrying (timeouts, dropped connections, retryable server errors), sinks wrap errors in it
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return "transient: " + e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// a batch failed only because documents with its _ids already exist. After a retry that means
+// the failed attempt wrote them, sinks wrap such errors in it
+type DuplicateIDError struct {
+	Err error
+}
+
+func (e *DuplicateIDError) Error() string {
+	return "duplicate _id: " + e.Err.Error()
+}
+
+func (e *DuplicateIDError) Unwrap() error {
+	return e.Err
+}
+
+// reports exactly which batch of a collection could not be written
+type BatchError struct {
+	Collection string
+	Batch      int // 1 based
+	Batches    int
+	First      int // index of the first document in the batch
+	Last       int // index of the last document in the batch
+	Attempts   int
+	Err        error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("writing %s batch %d/%d (documents %d-%d) failed after %d attempt(s): %v",
+		e.Collection, e.Batch, e.Batches, e.First, e.Last, e.Attempts, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// splits documents into batches of at most maxDocs documents and about maxBytes of bson,
+// a single document larger than maxBytes gets a batch of its own
+func SplitBatches(docs []interface{}, maxDocs, maxBytes int) ([][]interface{}, error) {
+	batches := [][]interface{}{}
+	batch := []interface{}{}
+	batchBytes := 0
+
+	for i, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("estimating size of document %d: %w", i, err)
+		}
+
+		if len(batch) > 0 && (len(batch) >= maxDocs || batchBytes+len(raw) > maxBytes) {
+			batches = append(batches, batch)
+			batch = []interface{}{}
+			batchBytes = 0
+		}
+
+		batch = append(batch, doc)
+		batchBytes += len(raw)
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+// writes a batch of documents to a collection, e.g. Sink.WriteBatch
+type batchWriter func(ctx context.Context, colName string, docs []interface{}) error
+
+// writes one batch, retrying transient errors with exponential backoff, returns the attempts made.
+// Duplicate _ids are only written by an earlier attempt on a retry, on the first one they are an error
+func (s *MongoStore) writeBatchWithRetry(colName string, batch []interface{}, write batchWriter) (int, error) {
+	cfg := s.Config.Batch
+	backoff := cfg.Backoff.Duration
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout.Duration)
+		err := write(ctx, colName, batch)
+		cancel()
+
+		var duplicate *DuplicateIDError
+		if attempt > 1 && errors.As(err, &duplicate) {
+			return attempt, nil
+		}
+
+		var transient *TransientError
+		if err == nil || !errors.As(err, &transient) || attempt > cfg.Retries {
+			return attempt, err
+		}
+
+		fmt.Printf("\n - Retrying %s batch in %v (attempt %d/%d): %v\n", colName, backoff, attempt+1, cfg.Retries+1, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSplitBatches(t *testing.T) {
+	docs := func(n int) []interface{} {
+		list := []interface{}{}
+		for i := 0; i < n; i++ {
+			list = append(list, bson.M{"n": i})
+		}
+		return list
+	}
+
+	cases := []struct {
+		name     string
+		docs     []interface{}
+		maxDocs  int
+		maxBytes int
+		want     []int // documents per batch
+	}{
+		{name: "empty", docs: docs(0), maxDocs: 3, maxBytes: 1 << 20, want: []int{}},
+		{name: "exact multiple", docs: docs(6), maxDocs: 3, maxBytes: 1 << 20, want: []int{3, 3}},
+		{name: "remainder", docs: docs(7), maxDocs: 3, maxBytes: 1 << 20, want: []int{3, 3, 1}},
+		{name: "fewer than a batch", docs: docs(2), maxDocs: 3, maxBytes: 1 << 20, want: []int{2}},
+		{name: "byte limit", docs: docs(4), maxDocs: 10, maxBytes: 30, want: []int{2, 2}},
+		{name: "larger than the byte limit", docs: []interface{}{bson.M{"s": strings.Repeat("x", 100)}, bson.M{"n": 1}}, maxDocs: 10, maxBytes: 50, want: []int{1, 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			batches, err := SplitBatches(c.docs, c.maxDocs, c.maxBytes)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []int{}
+			for _, batch := range batches {
+				got = append(got, len(batch))
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("got batches of %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("got batches of %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteBatchWithRetry(t *testing.T) {
+	transient := &TransientError{Err: errors.New("timeout")}
+	duplicate := &DuplicateIDError{Err: errors.New("E11000 duplicate key error index: _id_")}
+	failed := errors.New("document failed validation")
+
+	cases := []struct {
+		name     string
+		results  []error // of each attempt, the last one repeats
+		attempts int
+		err      error
+	}{
+		{name: "first attempt", results: []error{nil}, attempts: 1},
+		{name: "retried", results: []error{transient, nil}, attempts: 2},
+		{name: "gives up", results: []error{transient}, attempts: 3, err: transient},
+		{name: "not transient", results: []error{failed}, attempts: 1, err: failed},
+		{name: "duplicate on the first attempt", results: []error{duplicate}, attempts: 1, err: duplicate},
+		{name: "duplicate after a retry", results: []error{transient, duplicate}, attempts: 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Batch = BatchConfig{Docs: 10, Bytes: 1 << 20, Timeout: Duration{time.Second}, Retries: 2, Backoff: Duration{time.Millisecond}}
+			s := &MongoStore{Config: cfg}
+
+			calls := 0
+			write := func(ctx context.Context, colName string, docs []interface{}) error {
+				err := c.results[len(c.results)-1]
+				if calls < len(c.results) {
+					err = c.results[calls]
+				}
+				calls++
+				return err
+			}
+
+			attempts, err := s.writeBatchWithRetry("posts", []interface{}{bson.M{}}, write)
+			if attempts != c.attempts || calls != c.attempts {
+				t.Errorf("got %d attempts and %d writes, want %d", attempts, calls, c.attempts)
+			}
+			if err != c.err {
+				t.Errorf("got error %v, want %v", err, c.err)
+			}
+		})
+	}
+}
@@ -7,15 +7,39 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Board constants
-var defaultBoards [][]string = [][]string{
-	{"general", "gen", "general discussion on general topics, generally."},
-	{"mathematics", "math", "math is for cool kids"},
-	{"programming", "pro", "i wrote a javascript C++ parser"},
-	{"technology", "tech", "technology is cool"},
-	{"science", "sci", "can we go to mars yet?"},
-	{"politics", "pol", "politics is a mess"},
-	{"history", "his", "history is cool"},
+// a board and how it's generated
+type BoardConfig struct {
+	Title       string `json:"title"`
+	Short       string `json:"short"`
+	Description string `json:"description"`
+
+	Weight     int          `json:"weight"`                // relative share of the threads of boards without a range of their own
+	Threads    *CountConfig `json:"threads,omitempty"`     // threads of the board, instead of a weighted share. Ignored unless enabled
+	Posts      *CountConfig `json:"posts,omitempty"`       // posts per thread, the global range when empty or not enabled
+	AssetTypes []AssetType  `json:"asset_types,omitempty"` // types uploads may have, any when empty
+	NSFW       bool         `json:"nsfw"`                  // threads may be flagged NSFW
+	MaxAssets  *int         `json:"max_assets,omitempty"`  // assets per thread or post at most, 0 for text only
+	Tags       []string     `json:"tags,omitempty"`        // tags every thread of the board has
+}
+
+// Board constants, busy boards get more threads. Thread lengths follow the global posts range
+var defaultBoards = []BoardConfig{
+	{Title: "general", Short: "gen", Description: "general discussion on general topics, generally.", Weight: 35, NSFW: true},
+	{Title: "mathematics", Short: "math", Description: "math is for cool kids", Weight: 5, AssetTypes: []AssetType{AssetTypeImage}, MaxAssets: intPtr(2), Tags: []string{"math"}},
+	{Title: "programming", Short: "pro", Description: "i wrote a javascript C++ parser", Weight: 20, AssetTypes: []AssetType{AssetTypeImage}, MaxAssets: intPtr(4), Tags: []string{"programming"}},
+	{Title: "technology", Short: "tech", Description: "technology is cool", Weight: 15, Tags: []string{"tech"}},
+	{Title: "science", Short: "sci", Description: "can we go to mars yet?", Weight: 8, Tags: []string{"science"}},
+	{Title: "politics", Short: "pol", Description: "politics is a mess", Weight: 14},
+	{Title: "history", Short: "his", Description: "history is cool", Weight: 3, Tags: []string{"history"}},
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// copy of the default boards, for a config to own
+func DefaultBoardConfigs() []BoardConfig {
+	return append([]BoardConfig{}, defaultBoards...)
 }
 
 type Board struct {
@@ -35,9 +59,9 @@ type Board struct {
 
 // New board
 func NewBoard(title, short, description string) *Board {
-	ts := time.Now().UTC()
+	ts := Now()
 	return &Board{
-		ID:          primitive.NewObjectID(),
+		ID:          NewObjectID(),
 		PostRef:     0,
 		Title:       title,
 		Short:       short,
@@ -49,26 +73,127 @@ func NewBoard(title, short, description string) *Board {
 }
 
 // returns the index of the configured board
-func GetBoardIndex(index int) (*Board, error) {
-	if index < 0 || index > len(defaultBoards) {
+func (s *MongoStore) GetBoardIndex(index int) (*Board, error) {
+	boards := s.Config.BoardDefs
+	if index < 0 || index >= len(boards) {
 		return nil, fmt.Errorf("invalid board index %d", index)
 	}
-	return NewBoard(defaultBoards[index][0], defaultBoards[index][1], defaultBoards[index][2]), nil
+	return NewBoard(boards[index].Title, boards[index].Short, boards[index].Description), nil
+}
+
+// settings of the board with the short name, defaults for boards that aren't configured (loaded
+// by an append run)
+func (cfg *Config) Board(short string) BoardConfig {
+	for _, b := range cfg.BoardDefs {
+		if b.Short == short {
+			return b
+		}
+	}
+	return BoardConfig{Short: short, Weight: 1}
+}
+
+// one of the configured boards has the short name
+func (cfg *Config) hasBoard(short string) bool {
+	for _, b := range cfg.BoardDefs {
+		if b.Short == short {
+			return true
+		}
+	}
+	return false
 }
 
 // Generate boards
 func (s *MongoStore) GenerateBoards() {
-	for index, v := range defaultBoards {
+	boards := s.Config.BoardDefs
+	for index, v := range boards {
 		fmt.Print("\033[G\033[K")
-		fmt.Printf(" - Generating Boards: %v/%v", index+1, len(defaultBoards))
+		fmt.Printf(" - Generating Boards: %v/%v", index+1, len(boards))
 
-		board := NewBoard(v[0], v[1], v[2])
+		board := NewBoard(v.Title, v.Short, v.Description)
+		board.CreatedAt = timePtr(s.timeline.Start)
+		board.UpdatedAt = timePtr(s.timeline.Start)
 		s.cBoards = append(s.cBoards, board)
 	}
 
 	fmt.Print("\n")
 }
 
+// boards a thread lands on, by weight. Boards with a thread range of their own get theirs on top
+// of the count shared by weight
+func (s *MongoStore) PlanThreadBoards(count int) []*Board {
+	plan := []*Board{}
+	weighted := []*Board{}
+	weights := []int{}
+	total := 0
+
+	for _, board := range s.cBoards {
+		cfg := s.Config.Board(board.Short)
+		if cfg.Threads != nil && cfg.Threads.Enabled {
+			for n := RandomIntBetween(cfg.Threads.Min, cfg.Threads.Max); n > 0; n-- {
+				plan = append(plan, board)
+			}
+			continue
+		}
+		if cfg.Weight > 0 {
+			weighted = append(weighted, board)
+			weights = append(weights, cfg.Weight)
+			total += cfg.Weight
+		}
+	}
+
+	for i := 0; i < count && total > 0; i++ {
+		num := RandomIntBetween(0, total)
+		for j, w := range weights {
+			if num < w {
+				plan = append(plan, weighted[j])
+				break
+			}
+			num -= w
+		}
+	}
+
+	// own ranges mixed in with the rest instead of coming first
+	rng.Shuffle(len(plan), func(i, j int) { plan[i], plan[j] = plan[j], plan[i] })
+	return plan
+}
+
+// posts of a new thread on the board, in its range or the global one
+func (s *MongoStore) RandomPostCount(board *Board, min, max int) int {
+	if r := s.Config.Board(board.Short).Posts; r != nil && r.Enabled {
+		return RandomIntBetween(r.Min, r.Max)
+	}
+	return RandomIntBetween(min, max)
+}
+
+// media of a thread or post on the board, capped at its max
+func (s *MongoStore) RandomBoardMediaCount(board *Board) int {
+	n := s.RandomMediaCount()
+	if max := s.Config.Board(board.Short).MaxAssets; max != nil && n > *max {
+		return *max
+	}
+	return n
+}
+
+// board's tags first, then those of tags it doesn't have
+func (s *MongoStore) WithBoardTags(board *Board, tags []string) []string {
+	merged := append([]string{}, s.Config.Board(board.Short).Tags...)
+	for _, tag := range tags {
+		if !containsString(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // Get Random Board ID
 func (s *MongoStore) GetRandomBoardID() primitive.ObjectID {
 	return s.cBoards[RandomIntBetween(0, len(s.cBoards))].ID
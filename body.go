@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// formats bodies are rendered in
+const (
+	BodyFormatHTML     = "html"
+	BodyFormatMarkdown = "markdown"
+	BodyFormatPlain    = "plain"
+)
+
+// how bodies are generated and rendered, frequencies are percentages
+type BodyConfig struct {
+	Format string `json:"format"` // html, markdown or plain
+
+	Headings  int `json:"headings"`  // chance of a heading before a paragraph
+	Lists     int `json:"lists"`     // chance of a block being a list
+	Code      int `json:"code"`      // chance of a block being a code block
+	Greentext int `json:"greentext"` // chance of a block being greentext
+	Links     int `json:"links"`     // chance of a paragraph holding a link
+	Spoilers  int `json:"spoilers"`  // chance of a paragraph holding a spoiler
+	Emphasis  int `json:"emphasis"`  // chance of a sentence being emphasized
+}
+
+// body settings of the run, set from the config before anything is generated
+var bodyConfig = BodyConfig{Format: BodyFormatHTML}
+
+func SetBodyConfig(c BodyConfig) {
+	bodyConfig = c
+}
+
+type BlockKind int
+
+const (
+	BlockParagraph BlockKind = iota
+	BlockHeading
+	BlockList
+	BlockCode
+	BlockGreentext
+	BlockQuoteLink
+)
+
+type SpanKind int
+
+const (
+	SpanText SpanKind = iota
+	SpanEmphasis
+	SpanStrong
+	SpanCode
+	SpanSpoiler
+	SpanLink
+)
+
+// inline run of text
+type Span struct {
+	Kind SpanKind
+	Text string
+	URL  string // links only
+}
+
+// block of a body, which fields are used depends on the kind
+type Block struct {
+	Kind  BlockKind
+	Level int        // heading level, 2-4
+	Spans []Span     // paragraphs, headings and greentext
+	Items [][]Span   // list items
+	Code  string     // code blocks
+	Quote *QuoteLink // quote link lines
+}
+
+// a generated body, independent of the format it ends up in
+type Body struct {
+	Blocks []Block
+}
+
+// generated body with between min and max paragraphs, after a line per quote link.
+// Headings, lists, code and greentext blocks come in at the configured rates
+func GetBody(quotes []QuoteLink, min, max int) *Body {
+	b := &Body{}
+	for i := range quotes {
+		b.Blocks = append(b.Blocks, Block{Kind: BlockQuoteLink, Quote: &quotes[i]})
+	}
+
+	pc := RandomIntBetween(min, max)
+	for i := 0; i < pc; i++ {
+		if i > 0 && chance(bodyConfig.Headings) {
+			b.Blocks = append(b.Blocks, Block{Kind: BlockHeading, Level: RandomIntBetween(2, 5), Spans: []Span{{Kind: SpanText, Text: GetTitleWords()}}})
+		}
+
+		num := RandomIntBetween(0, 100)
+		c := bodyConfig
+		switch {
+		case num < c.Lists:
+			b.Blocks = append(b.Blocks, GetListBlock())
+		case num < c.Lists+c.Code:
+			b.Blocks = append(b.Blocks, Block{Kind: BlockCode, Code: GetCodeSnippet()})
+		case num < c.Lists+c.Code+c.Greentext:
+			b.Blocks = append(b.Blocks, Block{Kind: BlockGreentext, Spans: []Span{{Kind: SpanText, Text: strings.TrimSpace(GetSentence())}}})
+		default:
+			b.Blocks = append(b.Blocks, Block{Kind: BlockParagraph, Spans: GetParagraphSpans()})
+		}
+	}
+
+	return b
+}
+
+// true pct percent of the time
+func chance(pct int) bool {
+	return RandomIntBetween(0, 100) < pct
+}
+
+// sentences of a paragraph, some emphasized, maybe holding a link or a spoiler
+func GetParagraphSpans() []Span {
+	sc := RandomIntBetween(1, 6)
+	mixed := RandomIntBetween(0, sc)
+	spans := []Span{}
+	for i := 0; i < sc; i++ {
+		sentence := GetSentence()
+		if i == mixed {
+			sentence = Unicodify(UnicodeBodies, sentence)
+		}
+		kind := SpanText
+		if chance(bodyConfig.Emphasis) {
+			kind = []SpanKind{SpanEmphasis, SpanStrong, SpanCode}[RandomIntBetween(0, 3)]
+		}
+		if kind == SpanText {
+			spans = append(spans, Span{Kind: SpanText, Text: sentence})
+		} else {
+			// the separating space stays outside the markup
+			spans = append(spans, Span{Kind: kind, Text: strings.TrimSpace(sentence)}, Span{Kind: SpanText, Text: " "})
+		}
+	}
+
+	if chance(bodyConfig.Links) {
+		word := GetWord()
+		url := fmt.Sprintf("https://example.com/%s/%s", word, GetSlug(6, 10))
+		spans = append(spans, Span{Kind: SpanLink, Text: word, URL: url}, Span{Kind: SpanText, Text: " "})
+	}
+	if chance(bodyConfig.Spoilers) {
+		spans = append(spans, Span{Kind: SpanSpoiler, Text: strings.TrimSpace(GetSentence())}, Span{Kind: SpanText, Text: " "})
+	}
+
+	return spans
+}
+
+// a few words, for headings
+func GetTitleWords() string {
+	wc := RandomIntBetween(2, 6)
+	words := make([]string, wc)
+	for i := range words {
+		words[i] = GetWord()
+	}
+	return strings.Join(words, " ")
+}
+
+// list of 2 to 5 short items
+func GetListBlock() Block {
+	ic := RandomIntBetween(2, 6)
+	items := make([][]Span, ic)
+	for i := range items {
+		items[i] = []Span{{Kind: SpanText, Text: strings.TrimSpace(GetSentence())}}
+	}
+	return Block{Kind: BlockList, Items: items}
+}
+
+// a few lines of code made of lorem identifiers
+func GetCodeSnippet() string {
+	lc := RandomIntBetween(2, 7)
+	lines := []string{fmt.Sprintf("func %s(%s int) int {", GetLoremWord(), GetLoremWord())}
+	for i := 0; i < lc; i++ {
+		lines = append(lines, fmt.Sprintf("\t%s := %s(%s, %d)", GetLoremWord(), GetLoremWord(), GetLoremWord(), RandomIntBetween(0, 100)))
+	}
+	lines = append(lines, "\treturn "+GetLoremWord(), "}")
+	return strings.Join(lines, "\n")
+}
+
+// words of the body's paragraphs, greentext and lists, what a reply would quote
+func (b *Body) Words() []string {
+	words := []string{}
+	for _, block := range b.Blocks {
+		switch block.Kind {
+		case BlockParagraph, BlockGreentext:
+			for _, s := range block.Spans {
+				words = append(words, strings.Fields(s.Text)...)
+			}
+		case BlockList:
+			for _, item := range block.Items {
+				for _, s := range item {
+					words = append(words, strings.Fields(s.Text)...)
+				}
+			}
+		}
+	}
+	return words
+}
+
+// renders a body in one format
+type BodyRenderer interface {
+	Render(b *Body) string
+}
+
+// renderer of the format, html when unknown
+func NewBodyRenderer(format string) BodyRenderer {
+	switch format {
+	case BodyFormatMarkdown:
+		return MarkdownRenderer{}
+	case BodyFormatPlain:
+		return PlainRenderer{}
+	default:
+		return HTMLRenderer{}
+	}
+}
+
+// renders the body in the configured format
+func RenderBody(b *Body) string {
+	return NewBodyRenderer(bodyConfig.Format).Render(b)
+}
+
+// html fragments, wrapped in the thread-body div the app styles
+type HTMLRenderer struct{}
+
+func (HTMLRenderer) Render(b *Body) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="thread-body"> `)
+	for _, block := range b.Blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			sb.WriteString("<p>" + htmlSpans(block.Spans) + "</p> ")
+		case BlockHeading:
+			fmt.Fprintf(&sb, "<h%d>%s</h%d> ", block.Level, htmlSpans(block.Spans), block.Level)
+		case BlockList:
+			sb.WriteString("<ul>")
+			for _, item := range block.Items {
+				sb.WriteString("<li>" + htmlSpans(item) + "</li>")
+			}
+			sb.WriteString("</ul> ")
+		case BlockCode:
+			sb.WriteString("<pre><code>" + html.EscapeString(block.Code) + "</code></pre> ")
+		case BlockGreentext:
+			sb.WriteString(`<p><span class="quote">&gt;` + htmlSpans(block.Spans) + "</span></p> ")
+		case BlockQuoteLink:
+			sb.WriteString("<p>" + block.Quote.HTML() + "</p> ")
+		}
+	}
+	sb.WriteString("</div> ")
+	return sb.String()
+}
+
+func htmlSpans(spans []Span) string {
+	var sb strings.Builder
+	for _, s := range spans {
+		text := html.EscapeString(s.Text)
+		switch s.Kind {
+		case SpanEmphasis:
+			sb.WriteString("<em>" + text + "</em>")
+		case SpanStrong:
+			sb.WriteString("<strong>" + text + "</strong>")
+		case SpanCode:
+			sb.WriteString("<code>" + text + "</code>")
+		case SpanSpoiler:
+			sb.WriteString(`<span class="spoiler">` + text + "</span>")
+		case SpanLink:
+			sb.WriteString(`<a href="` + html.EscapeString(s.URL) + `">` + text + "</a>")
+		default:
+			sb.WriteString(text)
+		}
+	}
+	return sb.String()
+}
+
+// markdown, spoilers as ||text|| and greentext as > lines
+type MarkdownRenderer struct{}
+
+func (MarkdownRenderer) Render(b *Body) string {
+	blocks := make([]string, 0, len(b.Blocks))
+	for _, block := range b.Blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			blocks = append(blocks, markdownSpans(block.Spans))
+		case BlockHeading:
+			blocks = append(blocks, strings.Repeat("#", block.Level)+" "+markdownSpans(block.Spans))
+		case BlockList:
+			items := make([]string, len(block.Items))
+			for i, item := range block.Items {
+				items[i] = "- " + markdownSpans(item)
+			}
+			blocks = append(blocks, strings.Join(items, "\n"))
+		case BlockCode:
+			blocks = append(blocks, "```\n"+block.Code+"\n```")
+		case BlockGreentext:
+			blocks = append(blocks, ">"+markdownSpans(block.Spans))
+		case BlockQuoteLink:
+			blocks = append(blocks, block.Quote.Markdown())
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "|", `\|`, "<", `\<`)
+
+func markdownSpans(spans []Span) string {
+	var sb strings.Builder
+	for _, s := range spans {
+		text := markdownEscaper.Replace(s.Text)
+		switch s.Kind {
+		case SpanEmphasis:
+			sb.WriteString("*" + text + "*")
+		case SpanStrong:
+			sb.WriteString("**" + text + "**")
+		case SpanCode:
+			sb.WriteString(markdownCode(s.Text))
+		case SpanSpoiler:
+			sb.WriteString("||" + text + "||")
+		case SpanLink:
+			sb.WriteString("[" + text + "](" + s.URL + ")")
+		default:
+			sb.WriteString(text)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// a CommonMark code span, fenced by more backticks than the longest run in the text and padded
+// when the text starts or ends with a backtick, or with a space on both ends which would be stripped
+func markdownCode(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") ||
+		(strings.HasPrefix(text, " ") && strings.HasSuffix(text, " ") && strings.Trim(text, " ") != "") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// plain text, markup dropped, links followed by their url
+type PlainRenderer struct{}
+
+func (PlainRenderer) Render(b *Body) string {
+	blocks := make([]string, 0, len(b.Blocks))
+	for _, block := range b.Blocks {
+		switch block.Kind {
+		case BlockParagraph, BlockHeading:
+			blocks = append(blocks, plainSpans(block.Spans))
+		case BlockList:
+			items := make([]string, len(block.Items))
+			for i, item := range block.Items {
+				items[i] = "- " + plainSpans(item)
+			}
+			blocks = append(blocks, strings.Join(items, "\n"))
+		case BlockCode:
+			blocks = append(blocks, block.Code)
+		case BlockGreentext:
+			blocks = append(blocks, ">"+plainSpans(block.Spans))
+		case BlockQuoteLink:
+			blocks = append(blocks, block.Quote.Plain())
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func plainSpans(spans []Span) string {
+	var sb strings.Builder
+	for _, s := range spans {
+		if s.Kind == SpanLink {
+			sb.WriteString(s.Text + " (" + s.URL + ")")
+		} else {
+			sb.WriteString(s.Text)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestMarkdownCode(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "go vet", want: "`go vet`"},
+		{name: "backtick inside", text: "a`b", want: "``a`b``"},
+		{name: "longest run", text: "a``b`c", want: "```a``b`c```"},
+		{name: "starts with a backtick", text: "`a", want: "`` `a ``"},
+		{name: "ends with a backtick", text: "a`", want: "`` a` ``"},
+		{name: "spaces on both ends", text: " a ", want: "`  a  `"},
+		{name: "space on one end", text: " a", want: "` a`"},
+		{name: "only spaces", text: "  ", want: "`  `"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := markdownCode(c.text); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
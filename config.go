@@ -0,0 +1,681 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// min/max range for a generated amount, an enabled flag toggles the whole collection
+type CountConfig struct {
+	Enabled bool `json:"enabled"`
+	Min     int  `json:"min"`
+	Max     int  `json:"max"`
+}
+
+// time.Duration that reads and prints as a string like "30s" in config files and flags
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return d.Set(str)
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// how documents are split up and retried when written to a sink
+type BatchConfig struct {
+	Docs    int      `json:"docs"`    // max documents per batch
+	Bytes   int      `json:"bytes"`   // max estimated bson bytes per batch
+	Timeout Duration `json:"timeout"` // per batch attempt
+	Retries int      `json:"retries"` // extra attempts on transient errors
+	Backoff Duration `json:"backoff"` // wait before the first retry, doubled every retry
+}
+
+// what the seeder is allowed to drop, see safety.go
+type SafetyConfig struct {
+	Allow []string `json:"allow"`  // database name patterns that may be dropped, empty allows any not denied
+	Deny  []string `json:"deny"`   // database name patterns that are never dropped, checked first
+	IKnow bool     `json:"i_know"` // allow dropping a database on a host that isn't local
+	Yes   bool     `json:"yes"`    // skip the interactive confirmation
+}
+
+// resolved seeder configuration (defaults < config file < env < flags)
+type Config struct {
+	ConfigFile string `json:"-"`
+
+	Boards          CountConfig `json:"boards"` // boards come from board_defs, only the toggle is used
+	Accounts        CountConfig `json:"accounts"`
+	AssetSources    CountConfig `json:"asset_sources"`
+	Articles        CountConfig `json:"articles"`
+	ArticleComments CountConfig `json:"article_comments"` // per article
+	Threads         CountConfig `json:"threads"`
+	Posts           CountConfig `json:"posts"`          // per thread
+	MediaPerPost    CountConfig `json:"media_per_post"` // per thread, post and comment
+
+	BoardDefs []BoardConfig `json:"board_defs"`
+
+	Seed   int64    `json:"seed"`   // 0 leaves the run unseeded
+	Epoch  string   `json:"epoch"`  // RFC3339 start of the simulated clock on seeded runs, and the end of their timeline
+	Window Duration `json:"window"` // how far back the generated history reaches
+
+	ExportDir       string `json:"export_dir"`       // write each collection as NDJSON extended json here
+	ExportCanonical bool   `json:"export_canonical"` // canonical instead of relaxed extended json
+	Offline         bool   `json:"offline"`          // never connect to MongoDB, without export_dir this is a dry run
+	Append          bool   `json:"append"`           // add to the existing database instead of replacing it
+
+	Batch      BatchConfig `json:"batch"`
+	Validators bool        `json:"validators"` // $jsonSchema validators derived from the models
+
+	Safety SafetyConfig `json:"safety"`
+
+	Hash        HashConfig     `json:"hash"`
+	Passwords   PasswordConfig `json:"passwords"`
+	DevAccounts []DevAccount   `json:"dev_accounts"` // always generated, before the random accounts
+	Credentials string         `json:"credentials"`  // write a manifest of every login here, .json or .csv
+
+	Deletes     DeleteConfig     `json:"deletes"`
+	ThreadFlags ThreadFlagConfig `json:"thread_flags"`
+	Body        BodyConfig       `json:"body"`
+	Text        TextConfig       `json:"text"`
+	Unicode     UnicodeConfig    `json:"unicode"`
+	Uploads     UploadConfig     `json:"uploads"`
+	AssetReuse  AssetReuseConfig `json:"asset_reuse"`
+
+	UniqueRetries int `json:"unique_retries"` // redraws of a taken username or email before it gets a suffix
+
+	Verify       bool `json:"verify"`        // check the references of the generated data before persisting it
+	VerifyLimit  int  `json:"verify_limit"`  // issues printed by a verification
+	VerifyStrict bool `json:"verify_strict"` // generation policy checks are issues instead of warnings
+
+	PrintConfig bool `json:"print_config"`
+}
+
+// configuration used when nothing else is provided
+func DefaultConfig() *Config {
+	return &Config{
+		Boards:          CountConfig{Enabled: true},
+		Accounts:        CountConfig{Enabled: true, Min: 150, Max: 300},
+		AssetSources:    CountConfig{Enabled: true, Min: 400, Max: 800},
+		Articles:        CountConfig{Enabled: true, Min: 20, Max: 60},
+		ArticleComments: CountConfig{Enabled: true, Min: 0, Max: 100},
+		Threads:         CountConfig{Enabled: true, Min: 200, Max: 500},
+		Posts:           CountConfig{Enabled: true, Min: 5, Max: 60},
+		MediaPerPost:    CountConfig{Enabled: true, Min: 0, Max: 9},
+		BoardDefs:       DefaultBoardConfigs(),
+		Epoch:           DefaultSeedEpoch.Format(time.RFC3339),
+		Window:          Duration{2 * 365 * 24 * time.Hour},
+		Batch: BatchConfig{
+			Docs:    1000,
+			Bytes:   8 * int(MB),
+			Timeout: Duration{30 * time.Second},
+			Retries: 3,
+			Backoff: Duration{500 * time.Millisecond},
+		},
+		Validators: true,
+		Hash:       HashConfig{Cost: bcrypt.DefaultCost, Reuse: true},
+		Passwords:  PasswordConfig{Default: "123", Length: 12},
+
+		Deletes: DeleteConfig{Posts: 3, Comments: 3, Assets: 2, Cascade: CascadeAnonymize},
+		Body: BodyConfig{
+			Format:    BodyFormatHTML,
+			Headings:  5,
+			Lists:     5,
+			Code:      3,
+			Greentext: 8,
+			Links:     5,
+			Spoilers:  3,
+			Emphasis:  10,
+		},
+		Text:       TextConfig{Order: 2},
+		AssetReuse: AssetReuseConfig{Skew: 1.1, Reuploads: 15},
+		// only boards that allow NSFW flag their threads NSFW
+		ThreadFlags:   ThreadFlagConfig{Default: FlagPolicy{Stickies: 2, Locked: 2, Hidden: 1, NSFW: 15}},
+		UniqueRetries: 5,
+		Safety: SafetyConfig{
+			Deny: []string{"admin", "local", "config", "*prod*", "*live*"},
+		},
+		VerifyLimit: 50,
+		PrintConfig: true,
+	}
+}
+
+// a single setting reachable from both the command line and the environment
+type configField struct {
+	Flag  string
+	Env   string
+	Usage string
+	Value flag.Value
+}
+
+type intValue struct{ p *int }
+
+func (v intValue) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.p)
+}
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
+
+type int64Value struct{ p *int64 }
+
+func (v int64Value) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.FormatInt(*v.p, 10)
+}
+
+func (v int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
+
+type float64Value struct{ p *float64 }
+
+func (v float64Value) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*v.p, 'g', -1, 64)
+}
+
+func (v float64Value) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v.p = f
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) String() string {
+	if v.p == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*v.p)
+}
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v.p = b
+	return nil
+}
+
+func (v boolValue) IsBoolFlag() bool { return true }
+
+type stringValue struct{ p *string }
+
+func (v stringValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+// comma separated list, setting it replaces the whole list
+type stringsValue struct{ p *[]string }
+
+func (v stringsValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strings.Join(*v.p, ",")
+}
+
+func (v stringsValue) Set(s string) error {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v.p = list
+	return nil
+}
+
+// the enable/min/max fields of a count config
+func countFields(name, env, usage string, c *CountConfig) []configField {
+	return []configField{
+		{Flag: name, Env: "SEED_" + env, Usage: "generate " + usage, Value: boolValue{&c.Enabled}},
+		{Flag: name + "-min", Env: "SEED_" + env + "_MIN", Usage: "minimum `count` of " + usage, Value: intValue{&c.Min}},
+		{Flag: name + "-max", Env: "SEED_" + env + "_MAX", Usage: "maximum `count` of " + usage, Value: intValue{&c.Max}},
+	}
+}
+
+// every configurable setting of cfg
+func (cfg *Config) fields() []configField {
+	fields := []configField{
+		{Flag: "boards", Env: "SEED_BOARDS", Usage: "generate boards", Value: boolValue{&cfg.Boards.Enabled}},
+	}
+	fields = append(fields, countFields("accounts", "ACCOUNTS", "accounts (plus one session each)", &cfg.Accounts)...)
+	fields = append(fields, countFields("asset-sources", "ASSET_SOURCES", "asset sources", &cfg.AssetSources)...)
+	fields = append(fields, countFields("articles", "ARTICLES", "articles", &cfg.Articles)...)
+	fields = append(fields, countFields("article-comments", "ARTICLE_COMMENTS", "comments per article", &cfg.ArticleComments)...)
+	fields = append(fields, countFields("threads", "THREADS", "threads", &cfg.Threads)...)
+	fields = append(fields, countFields("posts", "POSTS", "posts per thread", &cfg.Posts)...)
+	fields = append(fields, countFields("media", "MEDIA", "assets per thread, post or comment", &cfg.MediaPerPost)...)
+	fields = append(fields,
+		configField{Flag: "seed", Env: "SEED_SEED", Usage: "master `seed` for reproducible output, 0 for a random run", Value: int64Value{&cfg.Seed}},
+		configField{Flag: "epoch", Env: "SEED_EPOCH", Usage: "RFC3339 `time` the simulated clock starts at on seeded runs", Value: stringValue{&cfg.Epoch}},
+		configField{Flag: "window", Env: "SEED_WINDOW", Usage: "`duration` of history the timestamps are spread over, ending now (or at the epoch when seeded)", Value: &cfg.Window},
+		configField{Flag: "export", Env: "SEED_EXPORT_DIR", Usage: "`dir` to export every collection to as NDJSON extended json", Value: stringValue{&cfg.ExportDir}},
+		configField{Flag: "export-canonical", Env: "SEED_EXPORT_CANONICAL", Usage: "export canonical instead of relaxed extended json", Value: boolValue{&cfg.ExportCanonical}},
+		configField{Flag: "offline", Env: "SEED_OFFLINE", Usage: "skip MongoDB entirely, only export (or dry run without -export)", Value: boolValue{&cfg.Offline}},
+		configField{Flag: "append", Env: "SEED_APPEND", Usage: "add to the existing database instead of dropping it, new documents reference the existing ones", Value: boolValue{&cfg.Append}},
+		configField{Flag: "batch-docs", Env: "SEED_BATCH_DOCS", Usage: "max `count` of documents per insert batch", Value: intValue{&cfg.Batch.Docs}},
+		configField{Flag: "batch-bytes", Env: "SEED_BATCH_BYTES", Usage: "max estimated bson `bytes` per insert batch", Value: intValue{&cfg.Batch.Bytes}},
+		configField{Flag: "batch-timeout", Env: "SEED_BATCH_TIMEOUT", Usage: "`duration` each insert batch attempt may take", Value: &cfg.Batch.Timeout},
+		configField{Flag: "batch-retries", Env: "SEED_BATCH_RETRIES", Usage: "`count` of retries for a batch failing with a transient error", Value: intValue{&cfg.Batch.Retries}},
+		configField{Flag: "batch-backoff", Env: "SEED_BATCH_BACKOFF", Usage: "`duration` before the first retry, doubled on every retry", Value: &cfg.Batch.Backoff},
+		configField{Flag: "validators", Env: "SEED_VALIDATORS", Usage: "create collections with $jsonSchema validators derived from the models", Value: boolValue{&cfg.Validators}},
+		configField{Flag: "allow-db", Env: "SEED_ALLOW_DB", Usage: "comma separated database name `patterns` that may be dropped, empty allows any not denied", Value: stringsValue{&cfg.Safety.Allow}},
+		configField{Flag: "deny-db", Env: "SEED_DENY_DB", Usage: "comma separated database name `patterns` that are never dropped", Value: stringsValue{&cfg.Safety.Deny}},
+		configField{Flag: "i-know", Env: "SEED_I_KNOW", Usage: "allow dropping a database on a host that isn't local", Value: boolValue{&cfg.Safety.IKnow}},
+		configField{Flag: "yes", Env: "SEED_YES", Usage: "don't ask for confirmation before dropping the database", Value: boolValue{&cfg.Safety.Yes}},
+		configField{Flag: "bcrypt-cost", Env: "SEED_BCRYPT_COST", Usage: "bcrypt `cost` of account passwords, 4 hashes fastest", Value: intValue{&cfg.Hash.Cost}},
+		configField{Flag: "hash-workers", Env: "SEED_HASH_WORKERS", Usage: "`count` of parallel password hashers, 0 uses GOMAXPROCS", Value: intValue{&cfg.Hash.Workers}},
+		configField{Flag: "hash-reuse", Env: "SEED_HASH_REUSE", Usage: "hash each distinct password once and share the hash between accounts", Value: boolValue{&cfg.Hash.Reuse}},
+		configField{Flag: "password", Env: "SEED_PASSWORD", Usage: "`plaintext` password of every generated account", Value: stringValue{&cfg.Passwords.Default}},
+		configField{Flag: "random-passwords", Env: "SEED_RANDOM_PASSWORDS", Usage: "generate a password per account instead", Value: boolValue{&cfg.Passwords.Random}},
+		configField{Flag: "password-length", Env: "SEED_PASSWORD_LENGTH", Usage: "`length` of generated passwords", Value: intValue{&cfg.Passwords.Length}},
+		configField{Flag: "credentials", Env: "SEED_CREDENTIALS", Usage: "`path` to write every login to, JSON for a .json extension and CSV otherwise", Value: stringValue{&cfg.Credentials}},
+		configField{Flag: "delete-posts", Env: "SEED_DELETE_POSTS", Usage: "`percent` of posts soft deleted", Value: intValue{&cfg.Deletes.Posts}},
+		configField{Flag: "delete-comments", Env: "SEED_DELETE_COMMENTS", Usage: "`percent` of article comments soft deleted", Value: intValue{&cfg.Deletes.Comments}},
+		configField{Flag: "delete-assets", Env: "SEED_DELETE_ASSETS", Usage: "`percent` of assets soft deleted", Value: intValue{&cfg.Deletes.Assets}},
+		configField{Flag: "delete-cascade", Env: "SEED_DELETE_CASCADE", Usage: "what deleted accounts leave behind, `anonymize` or delete their content", Value: stringValue{&cfg.Deletes.Cascade}},
+		configField{Flag: "stickies", Env: "SEED_STICKIES", Usage: "`count` of sticky threads per board without a policy of its own", Value: intValue{&cfg.ThreadFlags.Default.Stickies}},
+		configField{Flag: "locked-threads", Env: "SEED_LOCKED_THREADS", Usage: "`percent` of open threads locked on boards without a policy of their own", Value: intValue{&cfg.ThreadFlags.Default.Locked}},
+		configField{Flag: "hidden-threads", Env: "SEED_HIDDEN_THREADS", Usage: "`percent` of threads hidden on boards without a policy of their own", Value: intValue{&cfg.ThreadFlags.Default.Hidden}},
+		configField{Flag: "body-format", Env: "SEED_BODY_FORMAT", Usage: "`format` of thread, post, article and comment bodies: html, markdown or plain", Value: stringValue{&cfg.Body.Format}},
+		configField{Flag: "corpus", Env: "SEED_CORPUS", Usage: "comma separated `paths` of text files or directories to train a markov chain on instead of lorem", Value: stringsValue{&cfg.Text.Corpus}},
+		configField{Flag: "markov-order", Env: "SEED_MARKOV_ORDER", Usage: "`words` of context of the markov chain", Value: intValue{&cfg.Text.Order}},
+		configField{Flag: "unicode", Env: "SEED_UNICODE", Usage: "`percent` of usernames, identity names, titles, body paragraphs, tags and file names mixed with emoji, CJK, RTL, combining marks, zero width characters or long unbroken strings", Value: intValue{&cfg.Unicode.Rate}},
+		configField{Flag: "unicode-classes", Env: "SEED_UNICODE_CLASSES", Usage: "comma separated `classes` the unicode profile mixes in: emoji, cjk, rtl, combining, zero_width, long", Value: stringsValue{&cfg.Unicode.Classes}},
+		configField{Flag: "upload-dir", Env: "SEED_UPLOAD_DIR", Usage: "`dir` to render real image files of asset sources into, with sizes and checksums of their content", Value: stringValue{&cfg.Uploads.Dir}},
+		configField{Flag: "upload-url", Env: "SEED_UPLOAD_URL", Usage: "base `url` the upload dir is served at, file urls when empty", Value: stringValue{&cfg.Uploads.URL}},
+		configField{Flag: "asset-skew", Env: "SEED_ASSET_SKEW", Usage: "zipf `exponent` of asset source popularity, above 1, higher reposts fewer sources more often", Value: float64Value{&cfg.AssetReuse.Skew}},
+		configField{Flag: "reuploads", Env: "SEED_REUPLOADS", Usage: "`percent` of uploads that re-upload media the uploader uploaded before", Value: intValue{&cfg.AssetReuse.Reuploads}},
+		configField{Flag: "unique-retries", Env: "SEED_UNIQUE_RETRIES", Usage: "`count` of redraws of a taken username or email before a numeric suffix is added", Value: intValue{&cfg.UniqueRetries}},
+		configField{Flag: "verify", Env: "SEED_VERIFY", Usage: "verify the references of the generated data before persisting it", Value: boolValue{&cfg.Verify}},
+		configField{Flag: "verify-limit", Env: "SEED_VERIFY_LIMIT", Usage: "`count` of issues a verification prints", Value: intValue{&cfg.VerifyLimit}},
+		configField{Flag: "strict", Env: "SEED_VERIFY_STRICT", Usage: "fail verification on generation policy checks (timestamps, thread flags, deletions, video metadata) instead of warning", Value: boolValue{&cfg.VerifyStrict}},
+		configField{Flag: "print-config", Env: "SEED_PRINT_CONFIG", Usage: "print the resolved configuration before seeding", Value: boolValue{&cfg.PrintConfig}},
+	)
+	return fields
+}
+
+// flag set bound to the fields of cfg
+func (cfg *Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("seeder", flag.ContinueOnError)
+	fs.Var(stringValue{&cfg.ConfigFile}, "config", "`path` to a JSON config file (env: SEED_CONFIG)")
+	for _, f := range cfg.fields() {
+		fs.Var(f.Value, f.Flag, fmt.Sprintf("%s (env: %s)", f.Usage, f.Env))
+	}
+	return fs
+}
+
+// applies any set environment variables to cfg
+func (cfg *Config) applyEnv() error {
+	for _, f := range cfg.fields() {
+		val, ok := os.LookupEnv(f.Env)
+		if !ok {
+			continue
+		}
+		// an empty value clears a string, numbers and toggles can't be empty and keep theirs
+		if val == "" {
+			switch f.Value.(type) {
+			case stringValue, stringsValue:
+			default:
+				continue
+			}
+		}
+		if err := f.Value.Set(val); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %w", val, f.Env, err)
+		}
+	}
+	return nil
+}
+
+// short names boards may be defined with, they end up in urls, quote links and html attributes
+var boardShortPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// decoders of the config file formats by extension, JSON for any other. YAML and TOML files are
+// decoded generically and go through JSON, so every format uses the json keys of Config
+var configDecoders = map[string]func(data []byte, v interface{}) error{
+	".yaml": yaml.Unmarshal,
+	".yml":  yaml.Unmarshal,
+	".toml": toml.Unmarshal,
+}
+
+// applies a JSON, YAML or TOML config file to cfg, keys not present in the file are left untouched
+func (cfg *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if decode, ok := configDecoders[strings.ToLower(filepath.Ext(path))]; ok {
+		fields := map[string]interface{}{}
+		if err := decode(data, &fields); err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if data, err = json.Marshal(fields); err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	// boards in the file replace the defaults, json would decode them into the default boards
+	boards := cfg.BoardDefs
+	cfg.BoardDefs = nil
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if cfg.BoardDefs == nil {
+		cfg.BoardDefs = boards
+	}
+	return nil
+}
+
+// checks every range is usable by RandomIntBetween
+func (cfg *Config) Validate() error {
+	ranges := map[string]CountConfig{
+		"accounts":         cfg.Accounts,
+		"asset-sources":    cfg.AssetSources,
+		"articles":         cfg.Articles,
+		"article-comments": cfg.ArticleComments,
+		"threads":          cfg.Threads,
+		"posts":            cfg.Posts,
+		"media":            cfg.MediaPerPost,
+	}
+	for name, c := range ranges {
+		if !c.Enabled {
+			continue
+		}
+		if c.Min < 0 || c.Max < c.Min {
+			return fmt.Errorf("invalid range for %s: min %d max %d", name, c.Min, c.Max)
+		}
+	}
+	if cfg.Batch.Docs < 1 || cfg.Batch.Bytes < 1 || cfg.Batch.Retries < 0 || cfg.Batch.Timeout.Duration <= 0 {
+		return errors.New("batch docs, bytes and timeout must be positive, retries must not be negative")
+	}
+	if cfg.Hash.Cost < bcrypt.MinCost || cfg.Hash.Cost > bcrypt.MaxCost || cfg.Hash.Workers < 0 {
+		return fmt.Errorf("bcrypt cost must be %d-%d and hash workers not negative", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if cfg.Passwords.Random && cfg.Passwords.Length < 1 {
+		return errors.New("generated passwords need a positive length")
+	}
+	for name, pct := range map[string]int{"posts": cfg.Deletes.Posts, "comments": cfg.Deletes.Comments, "assets": cfg.Deletes.Assets} {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("deleted %s must be a percentage, got %d", name, pct)
+		}
+	}
+	if cfg.Deletes.Cascade != CascadeAnonymize && cfg.Deletes.Cascade != CascadeDelete {
+		return fmt.Errorf("delete cascade must be %s or %s, got %q", CascadeAnonymize, CascadeDelete, cfg.Deletes.Cascade)
+	}
+	if cfg.Body.Format != BodyFormatHTML && cfg.Body.Format != BodyFormatMarkdown && cfg.Body.Format != BodyFormatPlain {
+		return fmt.Errorf("body format must be %s, %s or %s, got %q", BodyFormatHTML, BodyFormatMarkdown, BodyFormatPlain, cfg.Body.Format)
+	}
+	b := cfg.Body
+	for _, pct := range []int{b.Headings, b.Lists, b.Code, b.Greentext, b.Links, b.Spoilers, b.Emphasis} {
+		if pct < 0 || pct > 100 {
+			return errors.New("body frequencies must be percentages")
+		}
+	}
+	if b.Lists+b.Code+b.Greentext > 100 {
+		return errors.New("body lists, code and greentext can't take more than 100 percent of the blocks together")
+	}
+	if (len(cfg.Text.Corpus) > 0 || len(cfg.Text.Boards) > 0) && (cfg.Text.Order < 1 || cfg.Text.Order > 5) {
+		return fmt.Errorf("markov order must be 1-5, got %d", cfg.Text.Order)
+	}
+	rates := map[string]int{"unicode": cfg.Unicode.Rate}
+	for field, rate := range cfg.Unicode.Fields {
+		if !isEnumValue(field, unicodeFields) {
+			return fmt.Errorf("unicode rate for unknown field %q, fields are %s", field, strings.Join(unicodeFields, ", "))
+		}
+		rates["unicode "+field] = rate
+	}
+	for name, rate := range rates {
+		if rate < 0 || rate > 100 {
+			return fmt.Errorf("%s rate must be a percentage, got %d", name, rate)
+		}
+	}
+	for _, class := range cfg.Unicode.Classes {
+		if !isEnumValue(class, unicodeClasses) {
+			return fmt.Errorf("unknown unicode class %q, classes are %s", class, strings.Join(unicodeClasses, ", "))
+		}
+	}
+	shorts := map[string]bool{}
+	for i, b := range cfg.BoardDefs {
+		if b.Title == "" || !boardShortPattern.MatchString(b.Short) {
+			return fmt.Errorf("board %d needs a title and a short name of lowercase letters and digits", i)
+		}
+		if shorts[b.Short] {
+			return fmt.Errorf("board %q is defined more than once", b.Short)
+		}
+		shorts[b.Short] = true
+		if b.Weight < 0 || (b.MaxAssets != nil && *b.MaxAssets < 0) {
+			return fmt.Errorf("board %s: weight and max assets must not be negative", b.Short)
+		}
+		for name, r := range map[string]*CountConfig{"threads": b.Threads, "posts": b.Posts} {
+			if r != nil && (r.Min < 0 || r.Max < r.Min) {
+				return fmt.Errorf("board %s: invalid range for %s: min %d max %d", b.Short, name, r.Min, r.Max)
+			}
+		}
+		for _, t := range b.AssetTypes {
+			if !isEnumValue(string(t), schemaEnums[reflect.TypeOf(t)]) {
+				return fmt.Errorf("board %s: unknown asset type %q", b.Short, t)
+			}
+		}
+	}
+	policies := map[string]FlagPolicy{"default": cfg.ThreadFlags.Default}
+	for short, policy := range cfg.ThreadFlags.Boards {
+		policies["board "+short] = policy
+	}
+	for name, policy := range policies {
+		if policy.Stickies < 0 {
+			return fmt.Errorf("%s flag policy: stickies must not be negative", name)
+		}
+		for _, pct := range []int{policy.Locked, policy.Hidden, policy.NSFW} {
+			if pct < 0 || pct > 100 {
+				return fmt.Errorf("%s flag policy: locked, hidden and nsfw must be percentages", name)
+			}
+		}
+	}
+	if cfg.Uploads.URL != "" && cfg.Uploads.Dir == "" {
+		return errors.New("upload url needs an upload dir to serve")
+	}
+	if cfg.Uploads.URL != "" {
+		if u, err := url.Parse(cfg.Uploads.URL); err != nil || u.Scheme == "" {
+			return fmt.Errorf("upload url must be an absolute url, got %q", cfg.Uploads.URL)
+		}
+	}
+	if cfg.AssetReuse.Skew <= 1 {
+		return fmt.Errorf("asset skew must be above 1, got %g", cfg.AssetReuse.Skew)
+	}
+	if cfg.AssetReuse.Reuploads < 0 || cfg.AssetReuse.Reuploads > 100 {
+		return fmt.Errorf("reuploads must be a percentage, got %d", cfg.AssetReuse.Reuploads)
+	}
+	if cfg.UniqueRetries < 0 {
+		return errors.New("unique retries must not be negative")
+	}
+	devUnique := NewUniqueRegistry(0)
+	for i, dev := range cfg.DevAccounts {
+		if !devUnique.Reserve(UniqueUsername, dev.Username) || !devUnique.Reserve(UniqueEmail, dev.Email) {
+			return fmt.Errorf("dev account %d reuses the username or email of an earlier one", i)
+		}
+		if dev.Username == "" || dev.Email == "" || dev.Password == "" {
+			return fmt.Errorf("dev account %d needs a username, email and password", i)
+		}
+		if dev.Role != "" && !isEnumValue(string(dev.Role), schemaEnums[reflect.TypeOf(dev.Role)]) {
+			return fmt.Errorf("dev account %s has an unknown role %q", dev.Username, dev.Role)
+		}
+		if dev.Status != "" && !isEnumValue(string(dev.Status), schemaEnums[reflect.TypeOf(dev.Status)]) {
+			return fmt.Errorf("dev account %s has an unknown status %q", dev.Username, dev.Status)
+		}
+	}
+	for _, pattern := range append(append([]string{}, cfg.Safety.Allow...), cfg.Safety.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid database pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.Window.Duration <= 0 {
+		return errors.New("window must be positive")
+	}
+	if _, err := cfg.SeedEpoch(); err != nil {
+		return fmt.Errorf("invalid epoch: %w", err)
+	}
+	if cfg.Append && (cfg.Offline || cfg.ExportDir != "") {
+		return errors.New("append writes to an existing MongoDB database, it can't be combined with offline or export")
+	}
+	for short, policy := range cfg.ThreadFlags.Boards {
+		if !cfg.hasBoard(short) {
+			return fmt.Errorf("thread flag policy for unknown board %q", short)
+		}
+		if policy.NSFW > 0 && !cfg.Board(short).NSFW {
+			return fmt.Errorf("thread flag policy flags NSFW threads on board %q, which doesn't allow them", short)
+		}
+	}
+	for short := range cfg.Text.Boards {
+		if !cfg.hasBoard(short) {
+			return fmt.Errorf("text corpus for unknown board %q", short)
+		}
+	}
+	if cfg.Boards.Enabled && cfg.Threads.Enabled && !cfg.boardsTakeThreads() {
+		return errors.New("threads need a board with a weight or a thread range of its own")
+	}
+	if cfg.Append {
+		// dependencies may already exist in the database, checkAppendable runs these once loaded
+		return nil
+	}
+	if cfg.Threads.Enabled && (!cfg.Boards.Enabled || !cfg.Accounts.Enabled) {
+		return errors.New("threads require boards and accounts to be enabled")
+	}
+	if cfg.Posts.Enabled && !cfg.Threads.Enabled {
+		return errors.New("posts require threads to be enabled")
+	}
+	if cfg.Articles.Enabled && !cfg.Accounts.Enabled {
+		return errors.New("articles require accounts to be enabled")
+	}
+	return nil
+}
+
+// some board gets threads, by weight or a range of its own
+func (cfg *Config) boardsTakeThreads() bool {
+	for _, b := range cfg.BoardDefs {
+		if b.Weight > 0 || (b.Threads != nil && b.Threads.Max > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsed start of the simulated clock
+func (cfg *Config) SeedEpoch() (time.Time, error) {
+	if cfg.Epoch == "" {
+		return DefaultSeedEpoch, nil
+	}
+	return time.Parse(time.RFC3339, cfg.Epoch)
+}
+
+// resolves the configuration from defaults, config file, environment and command line args
+func LoadConfig(args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// first pass only to find the config file, flags are applied again last
+	probe := DefaultConfig()
+	probe.ConfigFile = os.Getenv("SEED_CONFIG")
+	if err := probe.flagSet().Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := DefaultConfig()
+	cfg.ConfigFile = probe.ConfigFile
+
+	if cfg.ConfigFile != "" {
+		if err := cfg.applyFile(cfg.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.flagSet().Parse(args); err != nil {
+		return nil, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// prints the resolved configuration
+func (cfg *Config) Print() {
+	data, err := json.MarshalIndent(cfg, "  ", "  ")
+	if err != nil {
+		fmt.Println(" - Unable to print configuration:", err)
+		return
+	}
+	src := "defaults"
+	if cfg.ConfigFile != "" {
+		src = cfg.ConfigFile
+	}
+	hrPrint("Resolved Configuration (" + src + " < env < flags)")
+	fmt.Printf("  %s\n", data)
+}
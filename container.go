@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// video containers the upload mode writes, see VideoContainer
+var containerFileExtensions = []string{"mp4", "webm", "mov"}
+
+// a tiny valid video file of the container of m: headers describing the video's duration,
+// codec, size and frame rate, without any frames
+func VideoContainer(m *VideoMeta, width, height int) ([]byte, error) {
+	switch m.Container {
+	case "mp4", "quicktime":
+		return isoContainer(m, width, height), nil
+	case "webm":
+		return webmContainer(m, width, height), nil
+	}
+	return nil, fmt.Errorf("can't write a %s container", m.Container)
+}
+
+// sample entry codes of the codecs in iso media files
+var isoCodecs = map[string]string{
+	"h264":   "avc1",
+	"hevc":   "hvc1",
+	"av1":    "av01",
+	"mpeg4":  "mp4v",
+	"prores": "apcn",
+}
+
+// milliseconds, the timescale of iso movies and tracks
+const isoTimescale = 1000
+
+// unity matrix of iso movie and track headers
+var isoMatrix = u32s(0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000)
+
+// mp4 or quicktime file with one video track of no samples
+func isoContainer(m *VideoMeta, width, height int) []byte {
+	duration := uint32(math.Round(m.Duration * isoTimescale))
+
+	ftyp := box("ftyp", []byte("isom"), u32s(0x200), []byte("isomiso2mp41"))
+	if m.Container == "quicktime" {
+		ftyp = box("ftyp", []byte("qt  "), u32s(0x200), []byte("qt  "))
+	}
+
+	mvhd := fullBox("mvhd", 0, 0,
+		u32s(0, 0, isoTimescale, duration, 0x00010000), u16s(0x0100, 0), u32s(0, 0),
+		isoMatrix, u32s(0, 0, 0, 0, 0, 0), u32s(2))
+	tkhd := fullBox("tkhd", 0, 3,
+		u32s(0, 0, 1, 0, duration, 0, 0), u16s(0, 0, 0, 0),
+		isoMatrix, u32s(uint32(width)<<16, uint32(height)<<16))
+	mdhd := fullBox("mdhd", 0, 0, u32s(0, 0, isoTimescale, duration), u16s(0x55c4, 0)) // und
+	hdlr := fullBox("hdlr", 0, 0, u32s(0), []byte("vide"), u32s(0, 0, 0), []byte("VideoHandler\x00"))
+
+	compressor := make([]byte, 32)
+	entry := box(isoCodecs[m.VideoCodec],
+		make([]byte, 6), u16s(1), // data reference
+		u16s(0, 0), u32s(0, 0, 0), u16s(uint16(width), uint16(height)),
+		u32s(0x00480000, 0x00480000, 0), u16s(1), // 72 dpi, a frame per sample
+		compressor, u16s(0x0018, 0xffff),
+		isoCodecConfig(m, width, height))
+
+	stbl := box("stbl",
+		fullBox("stsd", 0, 0, u32s(1), entry),
+		fullBox("stts", 0, 0, u32s(0)),
+		fullBox("stsc", 0, 0, u32s(0)),
+		fullBox("stsz", 0, 0, u32s(0, 0)),
+		fullBox("stco", 0, 0, u32s(0)))
+	minf := box("minf",
+		fullBox("vmhd", 0, 1, u16s(0, 0, 0, 0)),
+		box("dinf", fullBox("dref", 0, 0, u32s(1), fullBox("url ", 0, 1))),
+		stbl)
+
+	moov := box("moov", mvhd, box("trak", tkhd, box("mdia", mdhd, hdlr, minf)))
+	return concat(ftyp, moov, box("mdat"))
+}
+
+// decoder configuration box of the sample entry, prores has none
+func isoCodecConfig(m *VideoMeta, width, height int) []byte {
+	picture := float64(align16(width) * align16(height))
+	rate := picture * m.FrameRate
+
+	switch m.VideoCodec {
+	case "h264":
+		sps, pps := h264ParameterSets(width, height, m.FrameRate)
+		return box("avcC",
+			[]byte{1, sps[1], sps[2], sps[3], 0xff}, // version, profile, compatibility, level, 4 byte lengths
+			[]byte{0xe0 | 1}, u16s(uint16(len(sps))), sps,
+			[]byte{1}, u16s(uint16(len(pps))), pps)
+	case "hevc":
+		// main profile, progressive frames, no parameter sets
+		return box("hvcC",
+			[]byte{1, 0x01}, u32s(0x60000000), u16s(0x9000, 0, 0),
+			[]byte{byte(codecLevel(hevcLevels, picture, rate))},
+			u16s(0xf000), []byte{0xfc, 0xfd, 0xf8, 0xf8},
+			u16s(uint16(math.Round(m.FrameRate*256))), []byte{0x0f, 0})
+	case "av1":
+		// main profile, 8 bit 4:2:0, no configuration obus
+		return box("av1C", []byte{0x81, byte(codecLevel(av1Levels, picture, rate)), 0x0c, 0})
+	}
+	return nil
+}
+
+// a codec level and the most luma samples of its frames and of a second
+type videoLevel struct {
+	Idc             int
+	Picture, Sample float64
+}
+
+var h264Levels = []videoLevel{
+	{31, 921600, 27648000},
+	{40, 2097152, 62914560},
+	{42, 2228224, 133693440},
+	{51, 9437184, 251658240},
+}
+
+var hevcLevels = []videoLevel{
+	{93, 983040, 33177600},
+	{120, 2228224, 66846720},
+	{123, 2228224, 133693440},
+	{153, 8912896, 534773760},
+}
+
+var av1Levels = []videoLevel{
+	{5, 1065024, 39938400},
+	{8, 2359296, 77856768},
+	{9, 2359296, 155713536},
+	{13, 8912896, 547430400},
+}
+
+// lowest level of the video, the highest when none fits
+func codecLevel(levels []videoLevel, picture, rate float64) int {
+	for _, l := range levels {
+		if picture <= l.Picture && rate <= l.Sample {
+			return l.Idc
+		}
+	}
+	return levels[len(levels)-1].Idc
+}
+
+func align16(n int) int {
+	return (n + 15) / 16 * 16
+}
+
+// baseline profile sequence and picture parameter set nal units of a progressive video of
+// the size, cropped from whole macroblocks
+func h264ParameterSets(width, height int, fps float64) ([]byte, []byte) {
+	picture := float64(align16(width) * align16(height))
+
+	var sps bitWriter
+	sps.bits(66, 8)   // baseline
+	sps.bits(0xc0, 8) // constrained to baseline and main
+	sps.bits(uint64(codecLevel(h264Levels, picture, picture*fps)), 8)
+	sps.ue(0)                             // sequence parameter set id
+	sps.ue(0)                             // 16 frame numbers
+	sps.ue(2)                             // picture order from the frame number
+	sps.ue(1)                             // reference frames
+	sps.bits(0, 1)                        // no gaps in frame numbers
+	sps.ue(uint64(align16(width)/16 - 1)) // macroblocks
+	sps.ue(uint64(align16(height)/16 - 1))
+	sps.bits(1, 1) // frames only
+	sps.bits(1, 1) // 8x8 direct inference
+	if cropX, cropY := align16(width)-width, align16(height)-height; cropX > 0 || cropY > 0 {
+		sps.bits(1, 1) // in 4:2:0 chroma samples
+		sps.ue(0)
+		sps.ue(uint64(cropX / 2))
+		sps.ue(0)
+		sps.ue(uint64(cropY / 2))
+	} else {
+		sps.bits(0, 1)
+	}
+	sps.bits(0, 1) // no vui
+
+	var pps bitWriter
+	pps.ue(0)      // picture parameter set id
+	pps.ue(0)      // of sequence parameter set 0
+	pps.bits(0, 1) // cavlc
+	pps.bits(0, 1)
+	pps.ue(0) // one slice group
+	pps.ue(0) // one reference of either list
+	pps.ue(0)
+	pps.bits(0, 3) // no weighted prediction
+	pps.se(0)      // initial qp of 26
+	pps.se(0)
+	pps.se(0)
+	pps.bits(1, 1) // deblocking filter controls
+	pps.bits(0, 2)
+
+	return nalUnit(0x67, sps.rbsp()), nalUnit(0x68, pps.rbsp())
+}
+
+// nal unit of the header and payload, with emulation prevention bytes
+func nalUnit(header byte, rbsp []byte) []byte {
+	nal := []byte{header}
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros == 2 && b <= 3 {
+			nal = append(nal, 3)
+			zeros = 0
+		}
+		nal = append(nal, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return nal
+}
+
+// msb first bit writer of h264 syntax elements
+type bitWriter struct {
+	buf []byte
+	n   int // bits written
+}
+
+func (w *bitWriter) bits(v uint64, count int) {
+	for i := count - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 0x80 >> uint(w.n%8)
+		}
+		w.n++
+	}
+}
+
+// unsigned exp-golomb
+func (w *bitWriter) ue(v uint64) {
+	v++
+	size := 0
+	for x := v; x > 1; x >>= 1 {
+		size++
+	}
+	w.bits(0, size)
+	w.bits(v, size+1)
+}
+
+// signed exp-golomb
+func (w *bitWriter) se(v int64) {
+	if v > 0 {
+		w.ue(uint64(2*v - 1))
+	} else {
+		w.ue(uint64(-2 * v))
+	}
+}
+
+// the bits followed by the rbsp stop bit, padded to a byte
+func (w *bitWriter) rbsp() []byte {
+	w.bits(1, 1)
+	for w.n%8 != 0 {
+		w.bits(0, 1)
+	}
+	return w.buf
+}
+
+// iso base media box of the payloads
+func box(kind string, payload ...[]byte) []byte {
+	body := concat(payload...)
+	return concat(u32s(uint32(8+len(body))), []byte(kind), body)
+}
+
+// box with a version and flags
+func fullBox(kind string, version byte, flags uint32, payload ...[]byte) []byte {
+	head := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return box(kind, append([][]byte{head}, payload...)...)
+}
+
+// codec ids of matroska tracks
+var webmCodecs = map[string]string{
+	"vp8": "V_VP8",
+	"vp9": "V_VP9",
+	"av1": "V_AV1",
+}
+
+// matroska element ids used by webmContainer
+const (
+	ebmlHeader         = 0x1a45dfa3
+	ebmlVersion        = 0x4286
+	ebmlReadVersion    = 0x42f7
+	ebmlMaxIDLength    = 0x42f2
+	ebmlMaxSizeLength  = 0x42f3
+	ebmlDocType        = 0x4282
+	ebmlDocTypeVersion = 0x4287
+	ebmlDocTypeRead    = 0x4285
+	mkvSegment         = 0x18538067
+	mkvInfo            = 0x1549a966
+	mkvTimestampScale  = 0x2ad7b1
+	mkvDuration        = 0x4489
+	mkvMuxingApp       = 0x4d80
+	mkvWritingApp      = 0x5741
+	mkvTracks          = 0x1654ae6b
+	mkvTrackEntry      = 0xae
+	mkvTrackNumber     = 0xd7
+	mkvTrackUID        = 0x73c5
+	mkvTrackType       = 0x83
+	mkvCodecID         = 0x86
+	mkvDefaultDuration = 0x23e383
+	mkvVideo           = 0xe0
+	mkvPixelWidth      = 0xb0
+	mkvPixelHeight     = 0xba
+)
+
+// webm file with one video track and no clusters
+func webmContainer(m *VideoMeta, width, height int) []byte {
+	header := ebml(ebmlHeader,
+		ebmlUint(ebmlVersion, 1),
+		ebmlUint(ebmlReadVersion, 1),
+		ebmlUint(ebmlMaxIDLength, 4),
+		ebmlUint(ebmlMaxSizeLength, 8),
+		ebml(ebmlDocType, []byte("webm")),
+		ebmlUint(ebmlDocTypeVersion, 4),
+		ebmlUint(ebmlDocTypeRead, 2))
+
+	info := ebml(mkvInfo,
+		ebmlUint(mkvTimestampScale, 1000000), // milliseconds
+		ebmlFloat(mkvDuration, m.Duration*1000),
+		ebml(mkvMuxingApp, []byte("opforu-seeder")),
+		ebml(mkvWritingApp, []byte("opforu-seeder")))
+
+	track := ebml(mkvTrackEntry,
+		ebmlUint(mkvTrackNumber, 1),
+		ebmlUint(mkvTrackUID, uint64(RandomIntBetween(1, math.MaxInt32))),
+		ebmlUint(mkvTrackType, 1), // video
+		ebml(mkvCodecID, []byte(webmCodecs[m.VideoCodec])),
+		ebmlUint(mkvDefaultDuration, uint64(math.Round(1e9/m.FrameRate))),
+		ebml(mkvVideo, ebmlUint(mkvPixelWidth, uint64(width)), ebmlUint(mkvPixelHeight, uint64(height))))
+
+	return concat(header, ebml(mkvSegment, info, ebml(mkvTracks, track)))
+}
+
+// ebml element of the payloads, the id already holds its length marker
+func ebml(id uint32, payload ...[]byte) []byte {
+	body := concat(payload...)
+	idBytes := u32s(id)
+	for len(idBytes) > 1 && idBytes[0] == 0 {
+		idBytes = idBytes[1:]
+	}
+	return concat(idBytes, ebmlSize(len(body)), body)
+}
+
+// shortest variable length integer of the size, all ones is reserved for unknown sizes
+func ebmlSize(size int) []byte {
+	n := 1
+	for n < 8 && uint64(size) >= 1<<(7*n)-1 {
+		n++
+	}
+	v := uint64(size) | 1<<(7*n)
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b[8-n:]
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return ebml(id, b)
+}
+
+func ebmlFloat(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return ebml(id, b)
+}
+
+func u32s(vals ...uint32) []byte {
+	b := make([]byte, 4*len(vals))
+	for i, v := range vals {
+		binary.BigEndian.PutUint32(b[i*4:], v)
+	}
+	return b
+}
+
+func u16s(vals ...uint16) []byte {
+	b := make([]byte, 2*len(vals))
+	for i, v := range vals {
+		binary.BigEndian.PutUint16(b[i*2:], v)
+	}
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	b := make([]byte, 0, n)
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
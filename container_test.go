@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// msb first reader of the bits written by bitWriter
+type bitReader struct {
+	buf []byte
+	n   int
+}
+
+func (r *bitReader) bits(count int) uint64 {
+	v := uint64(0)
+	for i := 0; i < count; i++ {
+		v = v<<1 | uint64(r.buf[r.n/8]>>(7-uint(r.n%8))&1)
+		r.n++
+	}
+	return v
+}
+
+func (r *bitReader) ue() uint64 {
+	size := 0
+	for r.bits(1) == 0 {
+		size++
+	}
+	return 1<<uint(size) - 1 + r.bits(size)
+}
+
+// rbsp of a nal unit, emulation prevention bytes removed
+func unescapeNal(nal []byte) []byte {
+	rbsp := []byte{}
+	zeros := 0
+	for _, b := range nal[1:] {
+		if zeros == 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		rbsp = append(rbsp, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return rbsp
+}
+
+func TestH264ParameterSets(t *testing.T) {
+	cases := []struct {
+		width, height int
+		fps           float64
+		level         byte
+	}{
+		{640, 360, 30, 31},
+		{854, 480, 60, 31},
+		{1280, 720, 30, 31},
+		{1280, 720, 60, 40},
+		{1920, 1080, 30, 40},
+		{1920, 1080, 60, 42},
+	}
+
+	for _, c := range cases {
+		sps, pps := h264ParameterSets(c.width, c.height, c.fps)
+		if sps[0] != 0x67 || pps[0] != 0x68 {
+			t.Fatalf("%dx%d: got nal headers %#x and %#x", c.width, c.height, sps[0], pps[0])
+		}
+
+		r := &bitReader{buf: unescapeNal(sps)}
+		profile, _, level := r.bits(8), r.bits(8), byte(r.bits(8))
+		r.ue()
+		r.ue()
+		r.ue()
+		r.ue()
+		r.bits(1)
+		width, height := int(r.ue()+1)*16, int(r.ue()+1)*16
+		r.bits(2)
+		if r.bits(1) == 1 {
+			left, right, top, bottom := r.ue(), r.ue(), r.ue(), r.ue()
+			width -= int(left+right) * 2
+			height -= int(top+bottom) * 2
+		}
+
+		if profile != 66 || level != c.level || width != c.width || height != c.height {
+			t.Errorf("%dx%d@%v: got profile %d level %d and %dx%d", c.width, c.height, c.fps, profile, level, width, height)
+		}
+	}
+}
+
+func TestIsoCodecConfig(t *testing.T) {
+	cases := []struct {
+		codec  string
+		config string // box of the sample entry, none when empty
+	}{
+		{"h264", "avcC"},
+		{"hevc", "hvcC"},
+		{"av1", "av1C"},
+		{"prores", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.codec, func(t *testing.T) {
+			m := &VideoMeta{Container: "mp4", VideoCodec: c.codec, FrameRate: 30, Duration: 10}
+			file := isoContainer(m, 1280, 720)
+
+			entry := bytes.Index(file, []byte(isoCodecs[c.codec]))
+			if entry < 0 {
+				t.Fatalf("no %s sample entry", isoCodecs[c.codec])
+			}
+			config := isoCodecConfig(m, 1280, 720)
+			if c.config == "" {
+				if len(config) != 0 {
+					t.Errorf("got a %q box", config[4:8])
+				}
+				return
+			}
+			if string(config[4:8]) != c.config {
+				t.Fatalf("got a %q box, want %s", config[4:8], c.config)
+			}
+			// after the entry's fields, as its child
+			if !bytes.Contains(file[entry:], config) {
+				t.Errorf("the %s box isn't in the sample entry", c.config)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// characters of generated passwords
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// account configured by hand, e.g. to always have a known admin login
+type DevAccount struct {
+	Username string        `json:"username"`
+	Email    string        `json:"email"`
+	Password string        `json:"password"`
+	Role     AccountRole   `json:"role"`   // admin when empty
+	Status   AccountStatus `json:"status"` // active when empty
+}
+
+// plaintext passwords of generated accounts
+type PasswordConfig struct {
+	Default string `json:"default"` // every account's password unless random
+	Random  bool   `json:"random"`  // a generated password per account
+	Length  int    `json:"length"`  // of generated passwords
+}
+
+// a login for one generated account, written to the credentials manifest
+type Credential struct {
+	Username  string        `json:"username"`
+	Email     string        `json:"email"`
+	Role      AccountRole   `json:"role"`
+	Status    AccountStatus `json:"status"`
+	Password  string        `json:"password"`
+	SessionID string        `json:"session_id,omitempty"`
+}
+
+// plaintext password for the next generated account
+func (s *MongoStore) NewPassword() string {
+	p := s.Config.Passwords
+	if p.Random {
+		return RandomString(passwordAlphabet, p.Length)
+	}
+	return p.Default
+}
+
+// remembers the login of an account, session is nil for accounts without one
+func (s *MongoStore) addCredential(account *Account, plaintext string, session *Session) {
+	cred := &Credential{
+		Username: account.Username,
+		Email:    account.Email,
+		Role:     account.Role,
+		Status:   account.Status,
+		Password: plaintext,
+	}
+	if session != nil {
+		cred.SessionID = session.SessionID
+	}
+	s.cCredentials = append(s.cCredentials, cred)
+}
+
+// writes every generated login to path, as JSON for a .json extension and CSV otherwise
+func (s *MongoStore) WriteCredentials(path string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s.cCredentials); err != nil {
+			return err
+		}
+	} else {
+		w := csv.NewWriter(f)
+		w.Write([]string{"username", "email", "role", "status", "password", "session_id"})
+		for _, c := range s.cCredentials {
+			w.Write([]string{c.Username, c.Email, string(c.Role), string(c.Status), c.Password, c.SessionID})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf(" - Wrote %d credentials to %s\n", len(s.cCredentials), path)
+
+	return f.Close()
+}
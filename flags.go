@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// how a board flags its threads, percentages are of the board's threads
+type FlagPolicy struct {
+	Stickies int `json:"stickies"` // open threads pinned per board
+	Locked   int `json:"locked"`   // percent of open threads locked, closed and archived ones mostly are
+	Hidden   int `json:"hidden"`   // percent of threads hidden, deleted ones always are
+	NSFW     int `json:"nsfw"`     // percent of threads marked NSFW, 0 on boards that don't allow it
+}
+
+// the default policy and the boards, by short name, that have their own
+type ThreadFlagConfig struct {
+	Default FlagPolicy            `json:"default"`
+	Boards  map[string]FlagPolicy `json:"boards"` // replaces the default for the board
+}
+
+// closed and archived threads locked, the rest was closed without locking
+const closedLockedPercent = 80
+
+// policy of the board
+func (c ThreadFlagConfig) Policy(board *Board) FlagPolicy {
+	if policy, ok := c.Boards[board.Short]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+// flags of a new thread on the board, stickies are picked per board once all threads exist
+func (s *MongoStore) GetWeightedThreadFlags(thread *Thread, board *Board) []ThreadFlag {
+	policy := s.Config.ThreadFlags.Policy(board)
+	flags := []ThreadFlag{}
+
+	switch thread.Status {
+	case ThreadStatusOpen:
+		if RandomIntBetween(0, 100) < policy.Locked {
+			flags = append(flags, ThreadFlagLocked)
+		}
+	case ThreadStatusClosed, ThreadStatusArchived:
+		if RandomIntBetween(0, 100) < closedLockedPercent {
+			flags = append(flags, ThreadFlagLocked)
+		}
+	case ThreadStatusDeleted:
+		flags = append(flags, ThreadFlagLocked)
+	}
+
+	if thread.Status == ThreadStatusDeleted || RandomIntBetween(0, 100) < policy.Hidden {
+		flags = append(flags, ThreadFlagHidden)
+	}
+	if RandomIntBetween(0, 100) < policy.NSFW && s.Config.Board(board.Short).NSFW {
+		flags = append(flags, ThreadFlagNSFW)
+	}
+
+	return flags
+}
+
+// pins the board's policy worth of stickies, picked from its open and visible threads.
+// Stickies the board already has (appending) count towards it
+func (s *MongoStore) PickStickies() {
+	byBoard := make(map[primitive.ObjectID][]*Thread)
+	pinned := make(map[primitive.ObjectID]int)
+	for _, t := range s.cThreads {
+		if t.HasFlag(ThreadFlagSticky) {
+			pinned[t.Board]++
+		} else if t.Status == ThreadStatusOpen && !t.HasFlag(ThreadFlagHidden) {
+			byBoard[t.Board] = append(byBoard[t.Board], t)
+		}
+	}
+
+	total := 0
+	for _, board := range s.cBoards {
+		candidates := byBoard[board.ID]
+		want := s.Config.ThreadFlags.Policy(board).Stickies - pinned[board.ID]
+		for i := 0; i < want && i < len(candidates); i++ {
+			// partial shuffle, the first want candidates are the stickies
+			j := RandomIntBetween(i, len(candidates))
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+			candidates[i].Flags = append(candidates[i].Flags, ThreadFlagSticky)
+			sortFlags(candidates[i].Flags)
+			total++
+		}
+	}
+
+	fmt.Printf(" - Pinned %d sticky threads\n", total)
+}
+
+// thread has the flag set
+func (t *Thread) HasFlag(flag ThreadFlag) bool {
+	for _, f := range t.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// flags in bit order, so documents don't depend on the order they were set in
+func sortFlags(flags []ThreadFlag) {
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+}
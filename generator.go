@@ -7,16 +7,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
-
-	gonanoid "github.com/matoous/go-nanoid/v2"
 )
 
 var HrSplit string = "\n-----------------------------------------------------\n"
@@ -358,9 +354,12 @@ var videoFileExtensions = []string{
 
 var SlugAlphabet string = "abcdefghijklmnopqrstuvwxyz0123456789"
 
-// random int between min and max
+// random int between min and max (max exclusive, returns min for an empty range)
 func RandomIntBetween(min, max int) int {
-	return rand.Intn(max-min) + min
+	if max <= min {
+		return min
+	}
+	return rng.Intn(max-min) + min
 }
 
 // returns a random category of words
@@ -384,7 +383,7 @@ func RandomLettersBetween(min, max int) string {
 	strlen := RandomIntBetween(min, max)
 	str := ""
 	for i := 0; i < strlen; i++ {
-		str = str + string(rune(RandomIntBetween(97, 122)))
+		str = str + string(rune(RandomIntBetween('a', 'z'+1)))
 	}
 	return str
 }
@@ -419,7 +418,7 @@ func GetUsername() string {
 		word = strconv.Itoa(RandomIntBetween(0, 99)) + prefix + strconv.Itoa(RandomIntBetween(0, 99)) + between + strconv.Itoa(RandomIntBetween(0, 99)) + suffix
 	}
 
-	return word
+	return Unicodify(UnicodeUsernames, word)
 }
 
 // some random email
@@ -456,34 +455,19 @@ func GetLoremWord() string {
 	return words_lorem[RandomIntBetween(0, len(words_lorem))]
 }
 
-// return random sentence
+// return random sentence from the active text source
 func GetSentence() string {
-	wc := RandomIntBetween(6, 14)
-	sentence := ""
-	for i := 0; i < wc; i++ {
-		sentence = sentence + GetLoremWord() + " "
-	}
-	return sentence
+	return activeText.Sentence()
 }
 
-// return random paragraph
-func GetParagraph() string {
-	sc := RandomIntBetween(1, 6)
-	paragraph := "<p>"
-	for i := 0; i < sc; i++ {
-		paragraph = paragraph + GetSentence()
-	}
-	return paragraph + "</p> "
+// return random word from the active text source
+func GetWord() string {
+	return activeText.Word()
 }
 
-// return random number of paragraphs between min and max
+// return random number of paragraphs between min and max, rendered in the configured format
 func GetParagraphsBetween(min, max int) string {
-	pc := RandomIntBetween(min, max)
-	paragraphs := "<div class=\"thread-body\"> "
-	for i := 0; i < pc; i++ {
-		paragraphs = paragraphs + GetParagraph()
-	}
-	return paragraphs + "</div> "
+	return RenderBody(GetBody(nil, min, max))
 }
 
 // weighted roles
@@ -553,8 +537,7 @@ func GetWeightedThreadRole() ThreadRole {
 // return random slug between min and max
 func GetSlug(min, max int) string {
 	slugLen := RandomIntBetween(min, max)
-	slug, _ := gonanoid.Generate(SlugAlphabet, slugLen)
-	return slug
+	return RandomString(SlugAlphabet, slugLen)
 }
 
 // identity alias prefixes
@@ -627,8 +610,9 @@ func GetRandomTags() []string {
 	words := map[string]int{}
 	tags := []string{}
 
-	for len(tags) < tagCount {
-		w := GetLoremWord()
+	// small vocabularies may not have enough distinct words
+	for tries := 0; len(tags) < tagCount && tries < tagCount*4; tries++ {
+		w := Unicodify(UnicodeTags, GetWord())
 		if ok := words[w]; ok == 0 {
 			words[w] = 1
 			tags = append(tags, w)
@@ -668,6 +652,9 @@ func GenerateAssetSource(index int) *AssetSource {
 	ix := RandomIntBetween(0, len(imageSourceSizes))
 
 	kind := GetRandomAssetType()
+	if kind == AssetTypeVideo {
+		return GenerateVideoSource(index)
+	}
 
 	sizes := strings.Split(imageSourceSizes[ix], "/")
 	width, _ = strconv.Atoi(sizes[0])
@@ -677,7 +664,7 @@ func GenerateAssetSource(index int) *AssetSource {
 	a_width, _ = strconv.Atoi(a_size[0])
 	a_height, _ = strconv.Atoi(a_size[1])
 
-	ts := time.Now().UTC()
+	ts := Now()
 	tsn := ts.UnixNano()
 
 	sourceURL, avatarURL := FormatImageUrls(index)
@@ -717,7 +704,7 @@ func GenerateAssetSource(index int) *AssetSource {
 	}
 
 	src := &AssetSource{
-		ID:        primitive.NewObjectID(),
+		ID:        NewObjectID(),
 		Details:   details,
 		AssetType: kind,
 		Uploaders: []primitive.ObjectID{},
@@ -792,13 +779,36 @@ func GetFileChecksumMD5(path string) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// hashes a password with bcrypt
-func HashPassword(plaintext string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(plaintext), bcrypt.DefaultCost)
+// md5 checksum of file, base64 encoded like GetChecksumFromStr
+func GetFileChecksumMD5Base64(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sha256 checksum of file, base64 encoded like GetChecksumFromStr
+func GetFileChecksumSHA256(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
-	return string(hashed), nil
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(hash.Sum(nil)), nil
 }
 
 // compare a hashed password with plaintext
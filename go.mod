@@ -3,11 +3,12 @@ module github.com/dd-web/opforu-seeder
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/google/uuid v1.3.1
 	github.com/joho/godotenv v1.5.1
-	github.com/matoous/go-nanoid/v2 v2.0.0
 	go.mongodb.org/mongo-driver v1.12.0
 	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// how account passwords are hashed
+type HashConfig struct {
+	Cost    int  `json:"cost"`    // bcrypt cost, 4-31
+	Workers int  `json:"workers"` // parallel hashers, 0 uses GOMAXPROCS
+	Reuse   bool `json:"reuse"`   // hash each distinct plaintext once and share the hash
+}
+
+// hashes every plaintext with bcrypt across a pool of workers, the result at each index belongs
+// to the plaintext at the same index. Salts are drawn up front in order, so seeded runs stay
+// reproducible no matter how the work is scheduled
+func HashPasswords(plaintexts []string, cfg HashConfig) ([]string, error) {
+	if cfg.Cost < bcrypt.MinCost || cfg.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", cfg.Cost)
+	}
+
+	// job per plaintext, or per distinct plaintext when reusing
+	jobOf := make([]int, len(plaintexts))
+	jobs := []string{}
+	seen := make(map[string]int)
+	for i, plaintext := range plaintexts {
+		if job, ok := seen[plaintext]; ok && cfg.Reuse {
+			jobOf[i] = job
+			continue
+		}
+		seen[plaintext] = len(jobs)
+		jobOf[i] = len(jobs)
+		jobs = append(jobs, plaintext)
+	}
+
+	salts := make([][]byte, len(jobs))
+	for i := range salts {
+		salts[i] = make([]byte, 16)
+		if err := ReadRandom(salts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	hashes := make([]string, len(jobs))
+	errs := make([]error, len(jobs))
+	queue := make(chan int)
+
+	var mu sync.Mutex
+	done := 0
+	step := len(jobs)/100 + 1
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				hashes[job], errs[job] = BcryptWithSalt([]byte(jobs[job]), cfg.Cost, salts[job])
+
+				mu.Lock()
+				done++
+				if done%step == 0 || done == len(jobs) {
+					fmt.Print("\033[G\033[K")
+					fmt.Printf(" - Hashing Passwords (%d workers, cost %d): %v/%v", workers, cfg.Cost, done, len(jobs))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(jobs) > 0 {
+		fmt.Print("\n")
+	}
+
+	for job, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("hashing password %d: %w", job, err)
+		}
+	}
+
+	result := make([]string, len(plaintexts))
+	for i, job := range jobOf {
+		result[i] = hashes[job]
+	}
+
+	return result, nil
+}
+
+// hashes the plaintexts into the password of the accounts at the same index
+func (s *MongoStore) hashAccountPasswords(accounts []*Account, plaintexts []string) error {
+	hashes, err := HashPasswords(plaintexts, s.Config.Hash)
+	if err != nil {
+		return err
+	}
+	for i, account := range accounts {
+		account.Password = hashes[i]
+	}
+	return nil
+}
+
+// hashes a single password like HashPasswords
+func HashPassword(plaintext string, cfg HashConfig) (string, error) {
+	hashes, err := HashPasswords([]string{plaintext}, cfg)
+	if err != nil {
+		return "", err
+	}
+	return hashes[0], nil
+}
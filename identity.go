@@ -25,11 +25,11 @@ type Identity struct {
 
 // New identity
 func NewIdentity(account, thread primitive.ObjectID, role ThreadRole) *Identity {
-	ts := time.Now().UTC()
+	ts := Now()
 	return &Identity{
-		ID:        primitive.NewObjectID(),
+		ID:        NewObjectID(),
 		Account:   account,
-		Name:      GetSlug(8, 10),
+		Name:      Unicodify(UnicodeIdentities, GetSlug(8, 10)),
 		Style:     GetIdentityStyle(),
 		Role:      role,
 		Status:    GetWeightedIdentityStatus(),
@@ -39,12 +39,13 @@ func NewIdentity(account, thread primitive.ObjectID, role ThreadRole) *Identity
 	}
 }
 
-// Return a user's identity for a thread - or create one if it doesn't exist
-func (s *MongoStore) GetUserThreadIdentity(account, thread primitive.ObjectID) *Identity {
+// Return a user's identity for a thread - or create one at the time of their first post if it doesn't exist
+func (s *MongoStore) GetUserThreadIdentity(account, thread primitive.ObjectID, at time.Time) *Identity {
 	if identity, ok := s.cUserThreadIdentitys[thread][account]; ok {
 		return identity
 	} else {
 		identity := NewIdentity(account, thread, GetWeightedThreadRole())
+		identity.CreatedAt, identity.UpdatedAt = timePtr(at), timePtr(at)
 		s.cUserThreadIdentitys[thread][account] = identity
 		s.cIdentites = append(s.cIdentites, identity)
 		return identity
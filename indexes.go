@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// declarative index definition, each sink applies it in its own way
+type IndexSpec struct {
+	Name               string
+	Keys               bson.D
+	Unique             bool
+	ExpireAfterSeconds *int32 // TTL index when set
+	Weights            bson.D // text index field weights
+	Partial            string // only documents that have this field are indexed
+}
+
+// sessions are removed by mongo as soon as they expire
+var sessionTTLSeconds int32 = 0
+
+// indexes created for each collection once everything is persisted
+var collectionIndexes = map[string][]IndexSpec{
+	"accounts": {
+		{Name: "username_unique", Keys: bson.D{{Key: "username", Value: 1}}, Unique: true},
+		{Name: "email_unique", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+		{Name: "role_status", Keys: bson.D{{Key: "role", Value: 1}, {Key: "status", Value: 1}}},
+	},
+	"sessions": {
+		{Name: "session_id_unique", Keys: bson.D{{Key: "session_id", Value: 1}}, Unique: true},
+		{Name: "account_id", Keys: bson.D{{Key: "account_id", Value: 1}}},
+		{Name: "expires_ttl", Keys: bson.D{{Key: "expires", Value: 1}}, ExpireAfterSeconds: &sessionTTLSeconds},
+	},
+	"boards": {
+		{Name: "short_unique", Keys: bson.D{{Key: "short", Value: 1}}, Unique: true},
+	},
+	"threads": {
+		{Name: "slug_unique", Keys: bson.D{{Key: "slug", Value: 1}}, Unique: true},
+		{Name: "board_updated", Keys: bson.D{{Key: "board", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Name: "creator", Keys: bson.D{{Key: "creator", Value: 1}}},
+		{Name: "tags", Keys: bson.D{{Key: "tags", Value: 1}}},
+		{
+			Name:    "title_body_text",
+			Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}},
+			Weights: bson.D{{Key: "title", Value: 10}, {Key: "body", Value: 1}},
+		},
+	},
+	"posts": {
+		{Name: "thread_post_number", Keys: bson.D{{Key: "thread", Value: 1}, {Key: "post_number", Value: 1}}},
+		{Name: "board_post_number_unique", Keys: bson.D{{Key: "board", Value: 1}, {Key: "post_number", Value: 1}}, Unique: true},
+		{Name: "creator", Keys: bson.D{{Key: "creator", Value: 1}}},
+		{Name: "body_text", Keys: bson.D{{Key: "body", Value: "text"}}},
+	},
+	"identities": {
+		// anonymized identities have no account, any number of them can share a thread
+		{Name: "thread_account_unique", Keys: bson.D{{Key: "thread", Value: 1}, {Key: "account", Value: 1}}, Unique: true, Partial: "account"},
+		{Name: "account", Keys: bson.D{{Key: "account", Value: 1}}},
+	},
+	"articles": {
+		{Name: "slug_unique", Keys: bson.D{{Key: "slug", Value: 1}}, Unique: true},
+		{Name: "author", Keys: bson.D{{Key: "author", Value: 1}}},
+		{Name: "status_created", Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Name: "tags", Keys: bson.D{{Key: "tags", Value: 1}}},
+		{
+			Name:    "title_body_text",
+			Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}},
+			Weights: bson.D{{Key: "title", Value: 10}, {Key: "body", Value: 1}},
+		},
+	},
+	"article_comments": {
+		{Name: "author", Keys: bson.D{{Key: "author", Value: 1}}},
+		{Name: "body_text", Keys: bson.D{{Key: "body", Value: "text"}}},
+	},
+	"article_authors": {
+		{Name: "author", Keys: bson.D{{Key: "author", Value: 1}}},
+	},
+	"asset_sources": {
+		{Name: "sha256_unique", Keys: bson.D{{Key: "details.source.hash_sha256", Value: 1}}, Unique: true},
+		{Name: "asset_type", Keys: bson.D{{Key: "asset_type", Value: 1}}},
+	},
+	"assets": {
+		{Name: "source_id", Keys: bson.D{{Key: "source_id", Value: 1}}},
+		{Name: "account_id", Keys: bson.D{{Key: "account_id", Value: 1}}},
+	},
+}
+
+// two documents sharing the key of a unique index
+type UniqueIndexError struct {
+	Collection string
+	Index      string
+	Key        string
+	First      int // index of the first document holding the key
+	Duplicate  int // index of the document repeating it
+}
+
+func (e *UniqueIndexError) Error() string {
+	return fmt.Sprintf("%s documents %d and %d violate unique index %s with key %s",
+		e.Collection, e.First, e.Duplicate, e.Index, e.Key)
+}
+
+// checks docs against every unique index of the collection before anything is written,
+// so offline sinks fail on the same data mongo would reject
+func CheckUniqueIndexes(colName string, docs []interface{}) error {
+	var unique []IndexSpec
+	for _, spec := range collectionIndexes[colName] {
+		if spec.Unique {
+			unique = append(unique, spec)
+		}
+	}
+	if len(unique) == 0 || len(docs) == 0 {
+		return nil
+	}
+
+	seen := make([]map[string]int, len(unique))
+	for i := range seen {
+		seen[i] = make(map[string]int, len(docs))
+	}
+
+	for i, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("encoding %s document %d: %w", colName, i, err)
+		}
+
+		for j, spec := range unique {
+			if spec.Partial != "" {
+				if _, err := bson.Raw(raw).LookupErr(strings.Split(spec.Partial, ".")...); err != nil {
+					continue
+				}
+			}
+			key := indexKey(bson.Raw(raw), spec.Keys)
+			if first, ok := seen[j][key]; ok {
+				return &UniqueIndexError{Collection: colName, Index: spec.Name, Key: key, First: first, Duplicate: i}
+			}
+			seen[j][key] = i
+		}
+	}
+
+	return nil
+}
+
+// key of a document in an index, missing fields count as null like they do in mongo
+func indexKey(doc bson.Raw, keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		val, err := doc.LookupErr(strings.Split(k.Key, ".")...)
+		if err != nil {
+			parts = append(parts, "null")
+			continue
+		}
+		parts = append(parts, val.String())
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckUniqueIndexes(t *testing.T) {
+	board, other := primitive.NewObjectID(), primitive.NewObjectID()
+	account := primitive.NewObjectID()
+
+	cases := []struct {
+		name       string
+		collection string
+		docs       []interface{}
+		index      string // violated, none when empty
+		first, dup int
+	}{
+		{name: "no documents", collection: "accounts"},
+		{name: "distinct", collection: "accounts", docs: []interface{}{
+			bson.M{"username": "a", "email": "a@x"},
+			bson.M{"username": "b", "email": "b@x"},
+		}},
+		{name: "username collision", collection: "accounts", docs: []interface{}{
+			bson.M{"username": "a", "email": "a@x"},
+			bson.M{"username": "b", "email": "b@x"},
+			bson.M{"username": "a", "email": "c@x"},
+		}, index: "username_unique", first: 0, dup: 2},
+		{name: "compound key", collection: "posts", docs: []interface{}{
+			bson.M{"board": board, "post_number": 1},
+			bson.M{"board": other, "post_number": 1},
+			bson.M{"board": board, "post_number": 1},
+		}, index: "board_post_number_unique", first: 0, dup: 2},
+		{name: "nested key", collection: "asset_sources", docs: []interface{}{
+			bson.M{"details": bson.M{"source": bson.M{"hash_sha256": "x"}}},
+			bson.M{"details": bson.M{"source": bson.M{"hash_sha256": "x"}}},
+		}, index: "sha256_unique", first: 0, dup: 1},
+		{name: "partial index skips missing fields", collection: "identities", docs: []interface{}{
+			bson.M{"thread": board},
+			bson.M{"thread": board},
+		}},
+		{name: "partial index", collection: "identities", docs: []interface{}{
+			bson.M{"thread": board, "account": account},
+			bson.M{"thread": board, "account": account},
+		}, index: "thread_account_unique", first: 0, dup: 1},
+		{name: "collection without unique indexes", collection: "assets", docs: []interface{}{
+			bson.M{"source_id": board},
+			bson.M{"source_id": board},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := CheckUniqueIndexes(c.collection, c.docs)
+			if c.index == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var unique *UniqueIndexError
+			if !errors.As(err, &unique) {
+				t.Fatalf("got error %v, want a *UniqueIndexError", err)
+			}
+			if unique.Collection != c.collection || unique.Index != c.index || unique.First != c.first || unique.Duplicate != c.dup {
+				t.Errorf("got %+v, want %s.%s documents %d and %d", *unique, c.collection, c.index, c.first, c.dup)
+			}
+		})
+	}
+}
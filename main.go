@@ -2,22 +2,23 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// seeder core engine - generated documents and the references between them, written out through a Sink
 type MongoStore struct {
-	MongoClient *mongo.Client
-	DB          *mongo.Database
-	StartTime   time.Time
-	DBName      string
+	Sink      Sink
+	StartTime time.Time
+	Config    *Config
 
 	PostRefs map[string]int
 
@@ -35,27 +36,184 @@ type MongoStore struct {
 	cAdmins []*primitive.ObjectID
 	cMods   []*primitive.ObjectID
 
+	cCredentials []*Credential // plaintext logins of the generated accounts
+
+	unique   *UniqueRegistry // usernames & emails handed out so far
+	timeline *Timeline       // when generated documents happened
+
+	cJoinOrder []*Account // cAccounts by join date, see accountsByJoin
+
 	cUserThreadIdentitys map[primitive.ObjectID]map[primitive.ObjectID]*Identity
 	cAssetSrcMap         map[int]*AssetSource
+
+	uploads          *uploadHistory // asset source popularity and who uploaded what, see reuse.go
+	exhaustedUploads int            // uploads that wanted more sources than their pool had
+
+	loaded map[primitive.ObjectID][sha256.Size]byte // fingerprints of the documents an append run loaded
 }
 
 func main() {
-	store := NewMongoStore()
+	args := os.Args[1:]
+	command := "seed"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "seed":
+		runSeed(cfg)
+	case "verify":
+		runVerify(cfg)
+	default:
+		log.Fatalf("unknown command %q, expected seed or verify", command)
+	}
+}
+
+// generates and persists a fresh dataset
+func runSeed(cfg *Config) {
+	if cfg.PrintConfig {
+		cfg.Print()
+	}
+
+	if cfg.Seed != 0 {
+		epoch, _ := cfg.SeedEpoch()
+		SeedRandom(cfg.Seed, epoch)
+	}
+
+	SetBodyConfig(cfg.Body)
+	SetUnicodeConfig(cfg.Unicode)
+	if err := SetUploadConfig(cfg.Uploads); err != nil {
+		log.Fatal(err)
+	}
+	if err := LoadTextSources(cfg.Text); err != nil {
+		log.Fatal(err)
+	}
+
+	sink, err := NewSinkFromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store := NewMongoStore(cfg, sink)
 
 	store.SetupDB()
 
-	store.GenerateAccounts(150, 300)
-	store.GenerateBoards()
-	store.GenerateAssetSources(400, 800)
-	store.GenerateArticles(20, 60)
-	store.GenerateThreads(200, 500)
-	store.GeneratePosts(5, 60)
+	if cfg.Append {
+		loader, ok := sink.(CollectionLoader)
+		if !ok {
+			log.Fatalf("appending needs a sink to load from, %T can't", sink)
+		}
+		if err := store.LoadExisting(context.Background(), loader); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	store.Generate()
+
+	// an append run only holds part of the dataset, it's verified once persisted
+	if cfg.Verify && !cfg.Append {
+		report := Verify(store.Dataset(), cfg.VerifyStrict)
+		report.Print(cfg.VerifyLimit)
+		if !report.OK() {
+			log.Fatal("generated data failed verification, nothing was persisted")
+		}
+	}
 
 	store.PersistAll()
 
+	if cfg.Credentials != "" {
+		if err := store.WriteCredentials(cfg.Credentials); err != nil {
+			log.Fatal("writing credentials: ", err)
+		}
+	}
+
+	if cfg.Verify && cfg.Append {
+		dataset, err := LoadDataset(context.Background(), sink.(CollectionLoader))
+		if err != nil {
+			log.Fatal(err)
+		}
+		report := Verify(dataset, cfg.VerifyStrict)
+		report.Print(cfg.VerifyLimit)
+		if !report.OK() {
+			log.Fatal("database failed verification after appending")
+		}
+	}
+
+	store.Close()
+
 	fmt.Printf("\n\n *** Finsihed Seeding Database *** \n\n")
 }
 
+// checks the references of a seeded database, or of an export with -offline
+func runVerify(cfg *Config) {
+	var loader CollectionLoader
+
+	if cfg.Offline {
+		if cfg.ExportDir == "" {
+			log.Fatal("verifying offline needs the export directory to read")
+		}
+		loader = NewFileSink(cfg.ExportDir, cfg.ExportCanonical)
+	} else {
+		mongoSink, err := NewMongoSinkFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer mongoSink.Close(context.Background())
+		loader = mongoSink
+	}
+
+	hrPrint("Verify - Loading Dataset")
+
+	dataset, err := LoadDataset(context.Background(), loader)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report := Verify(dataset, cfg.VerifyStrict)
+	report.Print(cfg.VerifyLimit)
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+// runs every enabled generator in dependency order
+func (s *MongoStore) Generate() {
+	c := s.Config
+
+	if c.Accounts.Enabled {
+		s.GenerateAccounts(c.Accounts.Min, c.Accounts.Max)
+	}
+	// boards are fixed, appending keeps the existing ones
+	if c.Boards.Enabled && len(s.cBoards) == 0 {
+		s.GenerateBoards()
+	}
+	if c.AssetSources.Enabled {
+		s.GenerateAssetSources(c.AssetSources.Min, c.AssetSources.Max)
+	}
+	if c.Articles.Enabled {
+		s.GenerateArticles(c.Articles.Min, c.Articles.Max)
+	}
+	if c.Threads.Enabled {
+		s.GenerateThreads(c.Threads.Min, c.Threads.Max)
+	}
+	if c.Posts.Enabled {
+		s.GeneratePosts(c.Posts.Min, c.Posts.Max)
+	}
+
+	s.PrintAssetReuse()
+	s.ApplySoftDeletes()
+	s.StampObjectIDs()
+}
+
 // primarily functions as an C++ assert
 func ensureEnvVarVaild(val string) string {
 	if val == "" {
@@ -84,35 +242,15 @@ func hrPrint(str string) {
 }
 
 // MongoDB Store - seeder core engine
-func NewMongoStore() *MongoStore {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal(err)
-	}
-
-	database := ensureEnvVarVaild(os.Getenv("MONGO_DATABASE"))
-
-	uri := ensureEnvVarVaild(getMongoDBConnectionString())
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-	if err != nil {
-		fmt.Println("Error connecting to MongoDB", err)
-		if err = client.Disconnect(ctx); err != nil {
-			log.Fatal(err)
-		}
-		log.Fatal(err)
-	}
-
-	db := client.Database(database)
-
+func NewMongoStore(cfg *Config, sink Sink) *MongoStore {
 	return &MongoStore{
-		MongoClient:          client,
-		DB:                   db,
-		DBName:               database,
+		Sink:                 sink,
+		Config:               cfg,
 		PostRefs:             make(map[string]int),
 		cUserThreadIdentitys: make(map[primitive.ObjectID]map[primitive.ObjectID]*Identity),
 		cAssetSrcMap:         make(map[int]*AssetSource),
+		unique:               NewUniqueRegistry(cfg.UniqueRetries),
+		timeline:             NewTimeline(cfg),
 		StartTime:            time.Now().UTC(),
 	}
 }
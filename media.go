@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// where real files of asset sources are written, picsum urls are used when no dir is set
+type UploadConfig struct {
+	Dir string `json:"dir"` // images and videos are written into this directory
+	URL string `json:"url"` // base url the directory is served at, file urls when empty
+}
+
+// upload settings of the run, set from the config before anything is generated
+var uploadConfig = UploadConfig{}
+
+// sets the upload settings and creates the directory
+func SetUploadConfig(c UploadConfig) error {
+	uploadConfig = c
+	if c.Dir == "" {
+		return nil
+	}
+	return os.MkdirAll(c.Dir, 0o755)
+}
+
+// formats rendered images are encoded in, the extensions they are stored with
+var uploadImageExtensions = []string{"png", "jpg", "gif"}
+
+// shades of the color ramp of a pattern, a gif palette holds them all
+const patternShades = 32
+
+// procedural image, the same at any size so avatars match their source
+type imagePattern struct {
+	kind    int
+	palette color.Palette
+	freq    float64 // stripes, checks or rings across the image
+	angle   float64 // direction of gradients and stripes
+	cx, cy  float64 // center of rings
+}
+
+const (
+	patternGradient = iota
+	patternStripes
+	patternChecks
+	patternRings
+	patternWaves
+	patternKinds
+)
+
+// random pattern over a ramp of two or three random colors
+func NewImagePattern() *imagePattern {
+	p := &imagePattern{
+		kind:  RandomIntBetween(0, patternKinds),
+		freq:  float64(RandomIntBetween(2, 16)),
+		angle: float64(RandomIntBetween(0, 360)) * math.Pi / 180,
+		cx:    float64(RandomIntBetween(0, 101)) / 100,
+		cy:    float64(RandomIntBetween(0, 101)) / 100,
+	}
+
+	stops := make([]color.RGBA, RandomIntBetween(2, 4))
+	for i := range stops {
+		stops[i] = color.RGBA{uint8(RandomIntBetween(0, 256)), uint8(RandomIntBetween(0, 256)), uint8(RandomIntBetween(0, 256)), 255}
+	}
+	for i := 0; i < patternShades; i++ {
+		p.palette = append(p.palette, rampColor(stops, float64(i)/(patternShades-1)))
+	}
+
+	return p
+}
+
+// color at t (0-1) of a ramp evenly spread over the stops
+func rampColor(stops []color.RGBA, t float64) color.RGBA {
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	f := pos - float64(i)
+	a, b := stops[i], stops[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// shade (0-1) of the point u, v of the unit square
+func (p *imagePattern) at(u, v float64) float64 {
+	along := u*math.Cos(p.angle) + v*math.Sin(p.angle)
+	switch p.kind {
+	case patternStripes:
+		return (math.Sin(along*p.freq*2*math.Pi) + 1) / 2
+	case patternChecks:
+		if (int(u*p.freq)+int(v*p.freq))%2 == 0 {
+			return 0.15
+		}
+		return 0.85
+	case patternRings:
+		return (math.Cos(math.Hypot(u-p.cx, v-p.cy)*p.freq*2*math.Pi) + 1) / 2
+	case patternWaves:
+		return (math.Sin(u*p.freq*math.Pi)*math.Cos(v*p.freq*math.Pi) + 1) / 2
+	default:
+		// along runs from -1.42 to 1.42 depending on the angle
+		return (along + math.Sqrt2) / (2 * math.Sqrt2)
+	}
+}
+
+// the pattern at width by height, the palette is the color ramp
+func (p *imagePattern) Render(width, height int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, width, height), p.palette)
+	for y := 0; y < height; y++ {
+		v := float64(y) / float64(height)
+		for x := 0; x < width; x++ {
+			t := p.at(float64(x)/float64(width), v)
+			img.Pix[y*img.Stride+x] = uint8(t*(patternShades-1) + 0.5)
+		}
+	}
+	return img
+}
+
+// encodes img in the format of the extension
+func encodeImage(img *image.Paletted, ext string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch ext {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, &gif.Options{NumColors: len(img.Palette)})
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: RandomIntBetween(70, 96)})
+	}
+	return buf.Bytes(), err
+}
+
+// renders the pattern into the upload dir
+func writeImageFile(p *imagePattern, name, ext string, width, height int) (*FileCtx, error) {
+	raw, err := encodeImage(p.Render(width, height), ext)
+	if err != nil {
+		return nil, err
+	}
+	return writeUploadFile(raw, name, ext, width, height)
+}
+
+// writes the file into the upload dir and describes it. Size and checksums are those of the
+// written file
+func writeUploadFile(raw []byte, name, ext string, width, height int) (*FileCtx, error) {
+	file := name + "." + ext
+	path := filepath.Join(uploadConfig.Dir, file)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return nil, err
+	}
+
+	sumMD5, err := GetFileChecksumMD5Base64(path)
+	if err != nil {
+		return nil, err
+	}
+	sumSHA256, err := GetFileChecksumSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileURL, err := uploadURL(path, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileCtx{
+		ServerFileName: name,
+		Height:         uint16(height),
+		Width:          uint16(width),
+		FileSize:       uint32(len(raw)),
+		URL:            fileURL,
+		Extension:      ext,
+		HashMD5:        sumMD5,
+		HashSHA256:     sumSHA256,
+	}, nil
+}
+
+// url of a written file, under the configured base url or a file url of its absolute path
+func uploadURL(path, file string) (string, error) {
+	if uploadConfig.URL != "" {
+		return strings.TrimRight(uploadConfig.URL, "/") + "/" + url.PathEscape(file), nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// replaces the placeholder files of src with written ones at the same dimensions and returns
+// the bytes written. Images get a source and an avatar in one format, videos a container of
+// their metadata and a jpg poster frame
+func WriteUploadFiles(src *AssetSource) (int, error) {
+	details := src.Details
+	p := NewImagePattern()
+
+	var source *FileCtx
+	var err error
+	ext := "jpg"
+	if src.AssetType == AssetTypeVideo {
+		var raw []byte
+		raw, err = VideoContainer(details.Video, int(details.Source.Width), int(details.Source.Height))
+		if err == nil {
+			source, err = writeUploadFile(raw, details.Source.ServerFileName, details.Source.Extension, int(details.Source.Width), int(details.Source.Height))
+		}
+	} else {
+		ext = uploadImageExtensions[RandomIntBetween(0, len(uploadImageExtensions))]
+		source, err = writeImageFile(p, details.Source.ServerFileName, ext, int(details.Source.Width), int(details.Source.Height))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("asset source %s: %w", src.ID.Hex(), err)
+	}
+	details.Source = source
+	// the written container has no frames, its bitrate is that of the file
+	if details.Video != nil && details.Video.Duration > 0 {
+		details.Video.Bitrate = uint32(math.Round(float64(source.FileSize) * 8 / details.Video.Duration))
+	}
+
+	avatar, err := writeImageFile(p, details.Avatar.ServerFileName, ext, int(details.Avatar.Width), int(details.Avatar.Height))
+	if err != nil {
+		return 0, fmt.Errorf("asset source %s avatar: %w", src.ID.Hex(), err)
+	}
+	details.Avatar = avatar
+
+	return int(source.FileSize) + int(avatar.FileSize), nil
+}
@@ -10,56 +10,120 @@ import (
 // collections to generate
 var collections []string = []string{"accounts", "boards", "threads", "posts", "articles", "article_comments", "article_authors", "identities", "asset_sources", "assets", "sessions"}
 
-// Drops and recreates all collections for a clean slate
+// Prepares the sink for a clean slate
 func (s *MongoStore) SetupDB() {
 	hrPrint("Setup - Reset & Regenerate")
-	fmt.Printf(" - Connected to MongoDB using database: %s\n", s.DBName)
 
-	fmt.Print(" - Dropping Collections")
-	err := s.DB.Drop(context.Background())
-	if err != nil {
-		log.Fatal("Error dropping database:", err)
+	if err := s.Sink.Open(context.Background(), collections); err != nil {
+		log.Fatal("Error setting up sink: ", err)
 	}
 
-	s.GenCollections()
 	hrPrint("Setup Finished - Now Generating Data")
 }
 
-// Generate Collections
-func (s *MongoStore) GenCollections() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// Generic document persistance through the configured sink, split into batches. When appending
+// only new documents are inserted, loaded documents are replaced if generating changed them.
+// New documents are checked against the loaded ones for unique keys
+func (s *MongoStore) PersistDocuments(docs []interface{}, colName string) error {
+	if err := CheckUniqueIndexes(colName, docs); err != nil {
+		return err
+	}
+
+	if s.loaded != nil {
+		var changed []interface{}
+		docs, changed = s.splitLoaded(docs)
+		if len(changed) > 0 {
+			if err := s.writeBatches(changed, colName, "Updated", s.replaceBatch); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(docs) == 0 {
+		fmt.Printf(" - Skipped %s, nothing generated\n", colName)
+		return nil
+	}
+
+	return s.writeBatches(docs, colName, "Persisted", s.Sink.WriteBatch)
+}
+
+// splits documents into batches and writes them one after the other with write
+func (s *MongoStore) writeBatches(docs []interface{}, colName, verb string, write batchWriter) error {
+	batches, err := SplitBatches(docs, s.Config.Batch.Docs, s.Config.Batch.Bytes)
+	if err != nil {
+		return fmt.Errorf("batching %s: %w", colName, err)
+	}
 
-	for i, name := range collections {
+	first := 0
+	for i, batch := range batches {
 		fmt.Print("\033[G\033[K")
-		fmt.Printf(" - Recreating Collections: %v/%v", i+1, len(collections))
-		err := s.DB.CreateCollection(ctx, name)
+		fmt.Printf(" - Persisting %s: batch %d/%d", colName, i+1, len(batches))
+
+		attempts, err := s.writeBatchWithRetry(colName, batch, write)
+		if err != nil {
+			fmt.Print("\n")
+			return &BatchError{
+				Collection: colName,
+				Batch:      i + 1,
+				Batches:    len(batches),
+				First:      first,
+				Last:       first + len(batch) - 1,
+				Attempts:   attempts,
+				Err:        err,
+			}
+		}
+
+		first += len(batch)
+	}
+
+	fmt.Print("\033[G\033[K")
+	fmt.Printf(" - %s %d %s documents in %d batch(es)\n", verb, len(docs), colName, len(batches))
+
+	return nil
+}
+
+// Creates the indexes of every collection
+func (s *MongoStore) CreateIndexes() error {
+	s.warnExpiredSessions()
+	for _, name := range collections {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := s.Sink.CreateIndexes(ctx, name, collectionIndexes[name])
+		cancel()
 		if err != nil {
-			fmt.Println("Error creating collection:", name, err)
-			continue
+			return fmt.Errorf("creating %s indexes: %w", name, err)
 		}
 	}
+	return nil
 }
 
-// Generic document persistance
-func (s *MongoStore) PersistDocuments(docs []interface{}, colName string) error {
+// warns when even the newest session expired already, as on seeded runs with an old -epoch.
+// The TTL index is created anyway and MongoDB removes those sessions soon after
+func (s *MongoStore) warnExpiredSessions() {
+	var newest time.Time
+	for _, session := range s.cSessions {
+		if session.Expires != nil && session.Expires.After(newest) {
+			newest = *session.Expires
+		}
+	}
+	if len(s.cSessions) == 0 || newest.After(time.Now()) {
+		return
+	}
+	fmt.Printf(" - Every session expired by %s, the sessions TTL index removes them (pick an -epoch closer to now to keep them)\n", newest.Format(time.RFC3339))
+}
+
+// Flushes and releases the sink
+func (s *MongoStore) Close() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	col := s.DB.Collection(colName)
-	_, err := col.InsertMany(ctx, docs)
-	if err != nil {
-		return err
+	if err := s.Sink.Close(ctx); err != nil {
+		log.Fatal(err)
 	}
-
-	fmt.Printf(" - Persisted %d %s documents to database\n", len(docs), colName)
-
-	return nil
 }
 
 // Persists all generated data to the database via their respective Persist fns
 func (s *MongoStore) PersistAll() {
-	hrPrint("Finished Generating Data - Persisting to Database")
+	hrPrint("Finished Generating Data - Persisting")
 
 	storeFns := []func() error{
 		s.PersistAccounts,
@@ -80,4 +144,8 @@ func (s *MongoStore) PersistAll() {
 			log.Fatal(err)
 		}
 	}
+
+	if err := s.CreateIndexes(); err != nil {
+		log.Fatal(err)
+	}
 }
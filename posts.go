@@ -25,13 +25,13 @@ type Post struct {
 }
 
 // New post
-func NewPost() *Post {
-	ts := time.Now().UTC()
+func NewPost(body string) *Post {
+	ts := Now()
 	return &Post{
-		ID:         primitive.NewObjectID(),
+		ID:         NewObjectID(),
 		PostNumber: 0,
 		Creator:    primitive.NilObjectID,
-		Body:       GetParagraphsBetween(1, 5),
+		Body:       body,
 		Assets:     []primitive.ObjectID{},
 		Board:      primitive.NilObjectID,
 		Thread:     primitive.NilObjectID,
@@ -42,36 +42,64 @@ func NewPost() *Post {
 
 // Generate Posts for each thread
 func (s *MongoStore) GeneratePosts(min, max int) {
+	// quotable posts of other threads, by board
+	boardPosts := make(map[primitive.ObjectID][]*Post)
+	quotes, mostReplies := 0, 0
+
 	for index, thread := range s.cThreads {
-		postCount := RandomIntBetween(min, max)
+		postBoard := s.GetBoardByID(thread.Board)
+		postCount := s.RandomPostCount(postBoard, min, max)
 		progress := int(float64(index) / float64(len(s.cThreads)) * float64(postCount*len(s.cThreads)-index))
 
 		fmt.Print("\033[G\033[K")
 		fmt.Printf(" - Generating Posts: %v/%v", progress, postCount*len(s.cThreads))
 
-		for i := 0; i < postCount; i++ {
-			mediaCt := RandomIntBetween(0, 9)
-			postBoard := s.GetBoardByID(thread.Board)
+		// after the thread and its latest activity (existing posts when appending), in order
+		postTimes := s.timeline.Sequence(latest(*thread.CreatedAt, *thread.UpdatedAt), postCount)
+		graph := newReplyGraph()
+		UseBoardText(postBoard.Short)
+		threadPosts := []*Post{}
 
-			s.PostRefs[postBoard.Short]++
-			postBoard.PostRef = s.PostRefs[postBoard.Short]
+		for i := 0; i < len(postTimes); i++ {
+			mediaCt := s.RandomBoardMediaCount(postBoard)
+			posted := postTimes[i]
+
+			// nobody had joined yet to make the post
+			postCreatorAccount := s.GetRandomAccountJoinedBefore(posted)
+			if postCreatorAccount == nil {
+				continue
+			}
+
+			pmedIds, err := s.GenerateAssetCount(mediaCt, postCreatorAccount.ID, posted, s.Config.Board(postBoard.Short).AssetTypes)
+			if err != nil {
+				s.reportUploads(err)
+			}
 
-			postCreatorAccount := s.GetRandomAccount()
-			postCreatorIdentity := s.GetUserThreadIdentity(postCreatorAccount.ID, thread.ID)
+			postCreatorIdentity := s.GetUserThreadIdentity(postCreatorAccount.ID, thread.ID, posted)
 
-			post := NewPost()
+			links := s.GetQuoteLinks(graph, thread, postBoard, posted, boardPosts)
+			quotes += len(links)
+			body := GetBody(links, 1, 5)
+
+			post := NewPost(RenderBody(body))
 			post.Board = thread.Board
 			post.Thread = thread.ID
 			post.Creator = postCreatorIdentity.ID
+			// numbered once the post is made so the board's sequence has no holes
+			s.PostRefs[postBoard.Short]++
+			postBoard.PostRef = s.PostRefs[postBoard.Short]
 			post.PostNumber = s.PostRefs[postBoard.Short]
+			post.Assets = pmedIds
+			post.CreatedAt = timePtr(posted)
+			post.UpdatedAt = timePtr(posted)
 
-			pmedIds, err := s.GenerateAssetCount(mediaCt, postCreatorAccount.ID)
-			if err != nil {
-				fmt.Println(err)
-				continue
+			// some posts get edited later on
+			if RandomIntBetween(0, 100) > 90 {
+				post.UpdatedAt = timePtr(s.timeline.After(posted))
 			}
 
-			post.Assets = pmedIds
+			touch(&thread.UpdatedAt, posted)
+			touch(&postBoard.UpdatedAt, posted)
 
 			if postCreatorIdentity.Role == "mod" && !thread.HasMod(postCreatorIdentity.ID) {
 				thread.Mods = append(thread.Mods, postCreatorIdentity.ID)
@@ -79,11 +107,20 @@ func (s *MongoStore) GeneratePosts(min, max int) {
 
 			thread.Posts = append(thread.Posts, post.ID)
 			s.cPosts = append(s.cPosts, post)
+			graph.add(post, body)
+			threadPosts = append(threadPosts, post)
+		}
+
+		boardPosts[thread.Board] = append(boardPosts[thread.Board], threadPosts...)
+		if most := graph.maxReplies(); most > mostReplies {
+			mostReplies = most
 		}
 	}
 
+	UseBoardText("")
 	fmt.Print("\033[G\033[K")
-	fmt.Printf(" - Generating Posts: %v/%v", len(s.cPosts), len(s.cPosts))
+	fmt.Printf(" - Generating Posts: %v/%v\n", len(s.cPosts), len(s.cPosts))
+	fmt.Printf(" - Linked %d quotes, the most quoted post has %d replies\n", quotes, mostReplies)
 }
 
 // Persist Posts
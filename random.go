@@ -0,0 +1,157 @@
+package main
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/blowfish"
+)
+
+// default clock start for seeded runs when no epoch is configured
+var DefaultSeedEpoch = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// every generator draws from this source, SeedRandom swaps it for a reproducible one
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// deterministic clock & object id state, only used once seeded
+var (
+	seeded          bool
+	seededWith      int64
+	seedClock       time.Time
+	objectIDProcess [5]byte
+	objectIDCounter uint32
+)
+
+// each clock read on a seeded run advances by this much so timestamps stay distinct
+var seedClockTick = time.Millisecond
+
+// reseeds every source of randomness so the same seed produces identical documents
+func SeedRandom(seed int64, epoch time.Time) {
+	rng = rand.New(rand.NewSource(seed))
+	seeded = true
+	seededWith = seed
+	seedClock = epoch.UTC()
+
+	rng.Read(objectIDProcess[:])
+	objectIDCounter = rng.Uint32() & 0xffffff
+}
+
+// mixes salt into the seeded source and the ObjectIDs derived from it. An append run mixes in
+// the documents it loaded, so appending with the seed of an earlier run doesn't draw its ids again
+func MixSeed(salt int64) {
+	if !seeded {
+		return
+	}
+	rng = rand.New(rand.NewSource(int64(uint64(seededWith) ^ uint64(salt)*0x9e3779b97f4a7c15)))
+
+	rng.Read(objectIDProcess[:])
+	objectIDCounter = rng.Uint32() & 0xffffff
+}
+
+// current time - a simulated clock when seeded, wall clock otherwise
+func Now() time.Time {
+	if !seeded {
+		return time.Now().UTC()
+	}
+	seedClock = seedClock.Add(seedClockTick)
+	return seedClock
+}
+
+// new ObjectID, derived from the seeded source when seeded
+func NewObjectID() primitive.ObjectID {
+	if !seeded {
+		return primitive.NewObjectID()
+	}
+
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(Now().Unix()))
+	copy(id[4:9], objectIDProcess[:])
+
+	objectIDCounter++
+	id[9] = byte(objectIDCounter >> 16)
+	id[10] = byte(objectIDCounter >> 8)
+	id[11] = byte(objectIDCounter)
+
+	return id
+}
+
+// new random (v4) uuid string
+func NewUUID() string {
+	if !seeded {
+		return uuid.NewString()
+	}
+	return uuid.Must(uuid.NewRandomFromReader(rng)).String()
+}
+
+// reads random bytes, from the seeded source when seeded
+func ReadRandom(p []byte) error {
+	if seeded {
+		_, err := rng.Read(p)
+		return err
+	}
+	_, err := io.ReadFull(cryptorand.Reader, p)
+	return err
+}
+
+// random string of length n made of characters from alphabet
+func RandomString(alphabet string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[RandomIntBetween(0, len(alphabet))]
+	}
+	return string(b)
+}
+
+// bcrypt's own base64 alphabet
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+// "OrpheanBeholderScryDoubt", the bcrypt IV
+var bcryptMagic = []byte("OrpheanBeholderScryDoubt")
+
+// bcrypt with a caller supplied 16 byte salt, golang.org/x/crypto/bcrypt always reads its
+// salt from crypto/rand which makes seeded runs unreproducible. The output is a standard
+// $2a$ hash verifiable with bcrypt.CompareHashAndPassword.
+func BcryptWithSalt(password []byte, cost int, salt []byte) (string, error) {
+	if len(salt) != 16 {
+		return "", fmt.Errorf("bcrypt salt must be 16 bytes, got %d", len(salt))
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return "", bcrypt.InvalidCostError(cost)
+	}
+	if len(password) > 72 {
+		password = password[:72]
+	}
+
+	// C implementations include the trailing NULL of the key during expansion
+	key := append(password[:len(password):len(password)], 0)
+
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return "", err
+	}
+
+	rounds := uint64(1) << uint(cost)
+	for i := uint64(0); i < rounds; i++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+
+	data := make([]byte, len(bcryptMagic))
+	copy(data, bcryptMagic)
+	for i := 0; i < 24; i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(data[i:i+8], data[i:i+8])
+		}
+	}
+
+	// only 23 of the 24 encrypted bytes are encoded, again for C compatibility
+	return fmt.Sprintf("$2a$%02d$%s%s", cost, bcryptEncoding.EncodeToString(salt), bcryptEncoding.EncodeToString(data[:23])), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// a small seeded run generated and persisted into memory
+func seedToMemory(t *testing.T, seed int64) *MemorySink {
+	t.Helper()
+
+	cfg := DefaultConfig()
+	cfg.Seed = seed
+	cfg.Offline = true
+	cfg.PrintConfig = false
+	cfg.Accounts = CountConfig{Enabled: true, Min: 20, Max: 21}
+	cfg.AssetSources = CountConfig{Enabled: true, Min: 30, Max: 31}
+	cfg.Articles = CountConfig{Enabled: true, Min: 3, Max: 4}
+	cfg.ArticleComments = CountConfig{Enabled: true, Min: 2, Max: 5}
+	cfg.Threads = CountConfig{Enabled: true, Min: 10, Max: 11}
+	cfg.Posts = CountConfig{Enabled: true, Min: 3, Max: 8}
+	cfg.Hash.Cost = bcrypt.MinCost
+	cfg.DevAccounts = []DevAccount{{Username: "dev_admin", Email: "dev_admin@example.test", Password: "123"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	epoch, _ := cfg.SeedEpoch()
+	SeedRandom(cfg.Seed, epoch)
+	SetBodyConfig(cfg.Body)
+	SetUnicodeConfig(cfg.Unicode)
+	if err := SetUploadConfig(cfg.Uploads); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadTextSources(cfg.Text); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := NewMemorySink()
+	store := NewMongoStore(cfg, sink)
+	store.SetupDB()
+	store.Generate()
+	store.PersistAll()
+	return sink
+}
+
+func TestBcryptWithSalt(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	cases := []struct {
+		name     string
+		password string
+		cost     int
+	}{
+		{name: "empty", password: "", cost: bcrypt.MinCost},
+		{name: "short", password: "123", cost: bcrypt.MinCost},
+		{name: "higher cost", password: "hunter2", cost: bcrypt.MinCost + 2},
+		{name: "unicode", password: "pässwörd ✓", cost: bcrypt.MinCost},
+		{name: "past 72 bytes", password: strings.Repeat("x", 100), cost: bcrypt.MinCost},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hash, err := BcryptWithSalt([]byte(c.password), c.cost, salt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(c.password)); err != nil {
+				t.Errorf("hash %s doesn't verify: %v", hash, err)
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(c.password+"!")); err == nil && len(c.password) < 72 {
+				t.Errorf("hash %s verifies a different password", hash)
+			}
+			if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != c.cost {
+				t.Errorf("got cost %d (%v), want %d", cost, err, c.cost)
+			}
+		})
+	}
+
+	if _, err := BcryptWithSalt([]byte("123"), bcrypt.MinCost, salt[:8]); err == nil {
+		t.Error("a short salt was accepted")
+	}
+	if _, err := BcryptWithSalt([]byte("123"), bcrypt.MinCost-1, salt); err == nil {
+		t.Error("a cost below the minimum was accepted")
+	}
+}
+
+// the same seed writes byte identical documents
+func TestSeededRunsAreIdentical(t *testing.T) {
+	first := seedToMemory(t, 42)
+	second := seedToMemory(t, 42)
+
+	for _, name := range collections {
+		a, b := first.Documents(name), second.Documents(name)
+		if len(a) != len(b) {
+			t.Errorf("%s: %d documents, then %d", name, len(a), len(b))
+			continue
+		}
+		for i := range a {
+			rawA, err := bson.Marshal(a[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			rawB, err := bson.Marshal(b[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rawA, rawB) {
+				t.Errorf("%s document %d differs:\n%s\n%s", name, i, bson.Raw(rawA), bson.Raw(rawB))
+				break
+			}
+		}
+	}
+
+	other := seedToMemory(t, 43)
+	rawA, _ := bson.Marshal(first.Documents("accounts")[0])
+	rawB, _ := bson.Marshal(other.Documents("accounts")[0])
+	if bytes.Equal(rawA, rawB) {
+		t.Error("another seed wrote the same first account")
+	}
+}
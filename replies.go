@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	stdhtml "html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a post quoting an earlier one, by its number on its board
+type QuoteLink struct {
+	Board      string // short name of the quoted post's board
+	Number     int    // post number on that board
+	CrossBoard bool   // the quoted post is on another board
+	Text       string // quoted words of the post, empty for a bare link
+}
+
+// link line, with the quoted text as greentext under it
+func (q QuoteLink) HTML() string {
+	// boards loaded by an append run may have any short name
+	board := stdhtml.EscapeString(q.Board)
+	link := fmt.Sprintf("&gt;&gt;%d", q.Number)
+	if q.CrossBoard {
+		link = fmt.Sprintf("&gt;&gt;&gt;/%s/%d", board, q.Number)
+	}
+	html := fmt.Sprintf(`<a class="quotelink" data-board="%s" data-post="%d">%s</a>`, board, q.Number, link)
+	if q.Text != "" {
+		html += `<br><span class="quote">&gt;` + stdhtml.EscapeString(q.Text) + `</span>`
+	}
+	return html
+}
+
+// link line and greentext in markdown
+func (q QuoteLink) Markdown() string {
+	if q.Text == "" {
+		return q.Plain()
+	}
+	return q.link() + "\n>" + markdownEscaper.Replace(q.Text)
+}
+
+// link line and greentext in plain text
+func (q QuoteLink) Plain() string {
+	if q.Text == "" {
+		return q.link()
+	}
+	return q.link() + "\n>" + q.Text
+}
+
+func (q QuoteLink) link() string {
+	if q.CrossBoard {
+		return fmt.Sprintf(">>>/%s/%d", q.Board, q.Number)
+	}
+	return fmt.Sprintf(">>%d", q.Number)
+}
+
+// >>N and >>>/board/N, escaped or not so it matches every body format. The board of an
+// escaped link is unescaped
+var quoteLinkPattern = regexp.MustCompile(`(?:&gt;|>){2}(?:(?:&gt;|>)/([^/\s]+)/)?(\d+)`)
+
+// quote links in a body of a post on board, without their text
+func ParseQuoteLinks(body, board string) []QuoteLink {
+	var links []QuoteLink
+	for _, m := range quoteLinkPattern.FindAllStringSubmatch(body, -1) {
+		number, _ := strconv.Atoi(m[2])
+		link := QuoteLink{Board: board, Number: number}
+		if m[1] != "" {
+			link.Board, link.CrossBoard = stdhtml.UnescapeString(m[1]), true
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// replies of a thread so far. Every post is in the urn once plus once per reply it got, drawing
+// from it quotes popular posts more (preferential attachment), reply counts follow a power law
+type replyGraph struct {
+	urn     []*Post
+	replies map[primitive.ObjectID]int
+	bodies  map[primitive.ObjectID]*Body
+}
+
+func newReplyGraph() *replyGraph {
+	return &replyGraph{
+		replies: make(map[primitive.ObjectID]int),
+		bodies:  make(map[primitive.ObjectID]*Body),
+	}
+}
+
+// post joins the thread and can be quoted from now on
+func (g *replyGraph) add(p *Post, body *Body) {
+	g.urn = append(g.urn, p)
+	g.bodies[p.ID] = body
+}
+
+// up to n distinct posts to quote, popular ones are likelier
+func (g *replyGraph) pick(n int) []*Post {
+	picked := []*Post{}
+	for tries := 0; len(picked) < n && tries < n*4 && len(g.urn) > 0; tries++ {
+		p := g.urn[RandomIntBetween(0, len(g.urn))]
+		if containsPost(picked, p) {
+			continue
+		}
+		picked = append(picked, p)
+	}
+	for _, p := range picked {
+		g.urn = append(g.urn, p)
+		g.replies[p.ID]++
+	}
+	return picked
+}
+
+// most replies a post of the thread got
+func (g *replyGraph) maxReplies() int {
+	most := 0
+	for _, n := range g.replies {
+		if n > most {
+			most = n
+		}
+	}
+	return most
+}
+
+func containsPost(posts []*Post, p *Post) bool {
+	for _, q := range posts {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// weighted number of posts a reply quotes, most quote none or one
+func GetWeightedQuoteCount() int {
+	num := RandomIntBetween(0, 100)
+	if num < 50 {
+		return 0
+	} else if num < 82 {
+		return 1
+	} else if num < 95 {
+		return 2
+	} else {
+		return 3
+	}
+}
+
+// quote links of a new post: earlier posts of its thread, now and then a post of another thread of
+// the board or of another board. Every quoted post was made before posted
+func (s *MongoStore) GetQuoteLinks(graph *replyGraph, thread *Thread, board *Board, posted time.Time, boardPosts map[primitive.ObjectID][]*Post) []QuoteLink {
+	links := []QuoteLink{}
+	for _, p := range graph.pick(GetWeightedQuoteCount()) {
+		links = append(links, QuoteLink{Board: board.Short, Number: p.PostNumber, Text: quotableText(graph.bodies[p.ID])})
+	}
+
+	num := RandomIntBetween(0, 100)
+	if num < 4 {
+		if p := earlierPost(boardPosts[board.ID], thread.ID, posted); p != nil {
+			links = append(links, QuoteLink{Board: board.Short, Number: p.PostNumber})
+		}
+	} else if num < 5 {
+		other := s.GetRandomBoard()
+		if other.ID != board.ID {
+			if p := earlierPost(boardPosts[other.ID], thread.ID, posted); p != nil {
+				links = append(links, QuoteLink{Board: other.Short, Number: p.PostNumber, CrossBoard: true})
+			}
+		}
+	}
+
+	return links
+}
+
+// random post of another thread made before t, nil if a few draws found none
+func earlierPost(posts []*Post, thread primitive.ObjectID, t time.Time) *Post {
+	for tries := 0; tries < 5 && len(posts) > 0; tries++ {
+		p := posts[RandomIntBetween(0, len(posts))]
+		if p.Thread != thread && p.CreatedAt.Before(t) {
+			return p
+		}
+	}
+	return nil
+}
+
+// a few words of the body worth quoting, empty now and then for a bare link
+func quotableText(body *Body) string {
+	if RandomIntBetween(0, 100) < 60 || body == nil {
+		return ""
+	}
+
+	words := body.Words()
+	if len(words) == 0 {
+		return ""
+	}
+
+	n := RandomIntBetween(3, 10)
+	if n > len(words) {
+		n = len(words)
+	}
+	start := RandomIntBetween(0, len(words)-n+1)
+	return strings.Join(words[start:start+n], " ")
+}
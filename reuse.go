@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// how uploads pick their asset sources
+type AssetReuseConfig struct {
+	Skew      float64 `json:"skew"`      // zipf exponent of source popularity, above 1, higher reposts fewer sources more
+	Reuploads int     `json:"reuploads"` // percent of uploads that re-upload media the uploader uploaded before
+}
+
+// flattens the head of the popularity curve, the top sources are reposted a lot without any
+// single one taking a large share of all uploads
+const zipfHead = 10
+
+// who uploaded which asset source so far, and how popular every source is. A few sources
+// are reposted everywhere and most are uploaded once or never, like real image boards
+type uploadHistory struct {
+	ranks []int   // source indexes from the most to the least popular
+	skew  float64 // zipf exponent of the pools
+	pools map[string]*sourcePool
+
+	byAccount map[primitive.ObjectID][]int                            // source indexes each account uploaded
+	first     map[primitive.ObjectID]map[primitive.ObjectID]time.Time // first upload of each uploader by source id
+	uploads   map[int]int                                             // uploads by source index
+}
+
+// sources of some asset types, in the order of their popularity
+type sourcePool struct {
+	ranks []int       // source indexes from the most to the least popular
+	pos   map[int]int // rank of each source index
+	zipf  *rand.Zipf  // rank of the next upload of a popular source
+}
+
+// popularity over the sources there are, the order of popularity is random so it has nothing
+// to do with the order sources were generated in
+func newUploadHistory(sources int, skew float64) *uploadHistory {
+	return &uploadHistory{
+		ranks:     rng.Perm(sources),
+		skew:      skew,
+		pools:     make(map[string]*sourcePool),
+		byAccount: make(map[primitive.ObjectID][]int),
+		first:     make(map[primitive.ObjectID]map[primitive.ObjectID]time.Time),
+		uploads:   make(map[int]int),
+	}
+}
+
+// the store's upload history, rebuilt when sources were added since
+func (s *MongoStore) uploadHistory() *uploadHistory {
+	if s.uploads == nil || len(s.uploads.ranks) != len(s.cAssetSrcMap) {
+		h := newUploadHistory(len(s.cAssetSrcMap), s.Config.AssetReuse.Skew)
+		if s.uploads != nil {
+			h.byAccount, h.first, h.uploads = s.uploads.byAccount, s.uploads.first, s.uploads.uploads
+		}
+		s.uploads = h
+	}
+	return s.uploads
+}
+
+// sources of the asset types, all of them for none. Sources keep their popularity order
+func (s *MongoStore) sourcePool(types []AssetType) *sourcePool {
+	h := s.uploadHistory()
+	key := fmt.Sprint(types)
+	if pool, ok := h.pools[key]; ok {
+		return pool
+	}
+
+	pool := &sourcePool{pos: make(map[int]int)}
+	for _, index := range h.ranks {
+		if len(types) == 0 || containsAssetType(types, s.cAssetSrcMap[index].AssetType) {
+			pool.pos[index] = len(pool.ranks)
+			pool.ranks = append(pool.ranks, index)
+		}
+	}
+	if len(pool.ranks) > 0 {
+		pool.zipf = rand.NewZipf(rng, h.skew, zipfHead, uint64(len(pool.ranks)-1))
+	}
+	h.pools[key] = pool
+	return pool
+}
+
+// rank of the source an account uploads next: now and then one of its own earlier uploads,
+// otherwise a source drawn by popularity
+func (p *sourcePool) pick(own []int, reuploads int) int {
+	if len(own) > 0 && chance(reuploads) {
+		if rank, ok := p.pos[own[RandomIntBetween(0, len(own))]]; ok {
+			return rank
+		}
+	}
+	return int(p.zipf.Uint64())
+}
+
+// records an upload and keeps the source's uploaders unique, ordered by their first upload.
+// Uploaders loaded with the source have no known upload time and stay first
+func (h *uploadHistory) add(index int, src *AssetSource, account primitive.ObjectID, at time.Time) {
+	h.uploads[index]++
+
+	first, ok := h.first[src.ID]
+	if !ok {
+		first = make(map[primitive.ObjectID]time.Time)
+		h.first[src.ID] = first
+	}
+
+	t, ok := first[account]
+	if ok && !at.Before(t) {
+		return
+	}
+	if !ok {
+		h.byAccount[account] = append(h.byAccount[account], index)
+		if containsObjectID(src.Uploaders, account) {
+			// loaded with the source, the upload happened before this run
+			first[account] = time.Time{}
+			return
+		}
+		src.Uploaders = append(src.Uploaders, account)
+	}
+	first[account] = at
+
+	sort.SliceStable(src.Uploaders, func(i, j int) bool {
+		return first[src.Uploaders[i]].Before(first[src.Uploaders[j]])
+	})
+}
+
+// uploads of the most uploaded source and the count of sources uploaded more than once
+func (h *uploadHistory) reposts() (int, int) {
+	most, reposted := 0, 0
+	for _, n := range h.uploads {
+		if n > most {
+			most = n
+		}
+		if n > 1 {
+			reposted++
+		}
+	}
+	return most, reposted
+}
+
+// prints how often sources were reused
+func (s *MongoStore) PrintAssetReuse() {
+	if s.uploads == nil || len(s.uploads.uploads) == 0 {
+		return
+	}
+	most, reposted := s.uploads.reposts()
+	fmt.Printf(" - Uploaded %d sources %d times, %d of them more than once, the most reposted %d times\n",
+		len(s.uploads.uploads), len(s.cAssets), reposted, most)
+	if s.exhaustedUploads > 0 {
+		fmt.Printf(" - %d uploads wanted more sources than their pool had and got fewer assets\n", s.exhaustedUploads)
+	}
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAssetType(types []AssetType, t AssetType) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// collection of the marker document, the seeder only drops databases holding one
+const (
+	markerCollection = "_seeder"
+	markerID         = "seeder"
+)
+
+// the seeder won't drop a database
+type DropRefusedError struct {
+	Database string
+	Reason   string
+}
+
+func (e *DropRefusedError) Error() string {
+	return fmt.Sprintf("refusing to seed database %q: %s", e.Database, e.Reason)
+}
+
+// checks that have to pass before anything is written: name patterns and a local host
+func (m *MongoSink) checkTarget() error {
+	refuse := func(format string, args ...interface{}) error {
+		return &DropRefusedError{Database: m.DBName, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if pattern, ok := mayDropDatabase(m.DBName, m.Safety); !ok {
+		if pattern == "" {
+			return refuse("it matches none of the allowed patterns %v", m.Safety.Allow)
+		}
+		return refuse("it matches the denied pattern %q", pattern)
+	}
+
+	if host, local := isLocalURI(m.URI); !local && !m.Safety.IKnow {
+		return refuse("host %s isn't local, pass -i-know if you really mean to seed it", host)
+	}
+
+	return nil
+}
+
+// every check that has to pass before the database is dropped: the target checks,
+// the marker document and an interactive confirmation
+func (m *MongoSink) checkDrop(ctx context.Context) error {
+	if err := m.checkTarget(); err != nil {
+		return err
+	}
+
+	counts, names, err := m.collectionCounts(ctx)
+	if err != nil {
+		return fmt.Errorf("listing collections: %w", err)
+	}
+
+	// nothing there to lose
+	if len(names) == 0 {
+		return nil
+	}
+
+	marked, err := m.hasMarker(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the marker document: %w", err)
+	}
+	if !marked {
+		return &DropRefusedError{Database: m.DBName, Reason: fmt.Sprintf("it wasn't created by the seeder (no %s.%s document), drop it by hand if it's safe to", markerCollection, markerID)}
+	}
+
+	if m.Safety.Yes {
+		return nil
+	}
+
+	return confirmDrop(m.DBName, names, counts)
+}
+
+// whether a database name may be dropped, with the deny pattern it matched or
+// an empty pattern when it isn't allowed
+func mayDropDatabase(name string, safety SafetyConfig) (string, bool) {
+	for _, pattern := range safety.Deny {
+		if ok, _ := path.Match(pattern, name); ok {
+			return pattern, false
+		}
+	}
+
+	if len(safety.Allow) == 0 {
+		return "", true
+	}
+
+	for _, pattern := range safety.Allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return "", true
+		}
+	}
+
+	return "", false
+}
+
+// whether every host of a connection string is the local machine, with the first one that isn't
+func isLocalURI(uri string) (string, bool) {
+	// srv records always point somewhere else, and resolving them needs a dns lookup
+	if rest := strings.TrimPrefix(uri, "mongodb+srv://"); rest != uri {
+		if i := strings.LastIndex(rest, "@"); i >= 0 {
+			rest = rest[i+1:]
+		}
+		return strings.SplitN(rest, "/", 2)[0], false
+	}
+
+	// never echo the uri itself, it may hold credentials
+	cs, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		return "(unparsable connection string)", false
+	}
+
+	for _, host := range cs.Hosts {
+		if strings.HasSuffix(host, ".sock") {
+			continue
+		}
+
+		name := host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			name = h
+		}
+
+		if name == "localhost" {
+			continue
+		}
+		if ip := net.ParseIP(name); ip != nil && ip.IsLoopback() {
+			continue
+		}
+
+		return host, false
+	}
+
+	return "", true
+}
+
+// estimated document count of every collection, and the sorted collection names
+func (m *MongoSink) collectionCounts(ctx context.Context) (map[string]int64, []string, error) {
+	names, err := m.DB.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(names)
+
+	counts := make(map[string]int64, len(names))
+	for _, name := range names {
+		n, err := m.DB.Collection(name).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		counts[name] = n
+	}
+
+	return counts, names, nil
+}
+
+func (m *MongoSink) hasMarker(ctx context.Context) (bool, error) {
+	n, err := m.DB.Collection(markerCollection).CountDocuments(ctx, bson.M{"_id": markerID})
+	return n > 0, err
+}
+
+// marks the database as created by the seeder, so later runs may drop it again
+func (m *MongoSink) writeMarker(ctx context.Context, collections []string) error {
+	marker := bson.M{
+		"_id":         markerID,
+		"created_at":  time.Now().UTC(),
+		"seed":        m.Seed,
+		"collections": collections,
+	}
+
+	_, err := m.DB.Collection(markerCollection).ReplaceOne(ctx, bson.M{"_id": markerID}, marker, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("writing the marker document: %w", err)
+	}
+
+	return nil
+}
+
+// lists what is about to be destroyed and waits for the database name to be typed back
+func confirmDrop(database string, names []string, counts map[string]int64) error {
+	if stat, err := os.Stdin.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return &DropRefusedError{Database: database, Reason: "no terminal to confirm on, pass -yes to drop it without asking"}
+	}
+
+	var total int64
+	fmt.Printf("\n - Dropping %s will destroy:\n", database)
+	for _, name := range names {
+		fmt.Printf("   %-24s %d documents\n", name, counts[name])
+		total += counts[name]
+	}
+	fmt.Printf("   %-24s %d documents\n\n", "total", total)
+
+	fmt.Printf(" - Type the database name to continue: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return &DropRefusedError{Database: database, Reason: "no confirmation given"}
+	}
+
+	if strings.TrimSpace(answer) != database {
+		return &DropRefusedError{Database: database, Reason: "confirmation didn't match"}
+	}
+
+	return nil
+}
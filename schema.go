@@ -0,0 +1,181 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// model the documents of each collection are made of, validators are derived from them
+var collectionModels = map[string]interface{}{
+	"accounts":         Account{},
+	"boards":           Board{},
+	"threads":          Thread{},
+	"posts":            Post{},
+	"articles":         Article{},
+	"article_comments": ArticleComment{},
+	"article_authors":  ArticleAuthor{},
+	"identities":       Identity{},
+	"asset_sources":    AssetSource{},
+	"assets":           Asset{},
+	"sessions":         Session{},
+}
+
+// allowed values of the string enums
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(AccountRole("")): {
+		string(AccountRoleUnknown), string(AccountRolePublic), string(AccountRoleUser), string(AccountRoleMod), string(AccountRoleAdmin),
+	},
+	reflect.TypeOf(AccountStatus("")): {
+		string(AccountStatusUnknown), string(AccountStatusActive), string(AccountStatusSuspended), string(AccountStatusBanned), string(AccountStatusDeleted),
+	},
+	reflect.TypeOf(ThreadStatus("")): {
+		string(ThreadStatusUnknown), string(ThreadStatusOpen), string(ThreadStatusClosed), string(ThreadStatusArchived), string(ThreadStatusDeleted),
+	},
+	reflect.TypeOf(ThreadRole("")): {
+		string(ThreadRoleUnknown), string(ThreadRoleUser), string(ThreadRoleMod), string(ThreadRoleCreator),
+	},
+	reflect.TypeOf(IdentityStatus("")): {
+		string(IdentityStatusUnknown), string(IdentityStatusActive), string(IdentityStatusSuspended), string(IdentityStatusBanned), string(IdentityStatusDeleted),
+	},
+	reflect.TypeOf(ArticleStatus("")): {
+		string(ArticleStatusDraft), string(ArticleStatusPublished), string(ArticleStatusArchived), string(ArticleStatusDeleted),
+	},
+	reflect.TypeOf(AssetType("")): {
+		string(AssetTypeImage), string(AssetTypeVideo),
+	},
+}
+
+// whether the string value of an enum is one of values
+func isEnumValue(v string, values []string) bool {
+	for _, value := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// $jsonSchema validator of a collection, nil if it has no model
+func CollectionValidator(colName string) bson.M {
+	model, ok := collectionModels[colName]
+	if !ok {
+		return nil
+	}
+
+	schema := JSONSchemaFor(reflect.TypeOf(model))
+	schema["title"] = colName
+
+	return bson.M{"$jsonSchema": schema}
+}
+
+// $jsonSchema of a type, following the bson tags and the way the driver encodes each kind
+func JSONSchemaFor(t reflect.Type) bson.M {
+	switch t {
+	case timeType:
+		return bson.M{"bsonType": "date"}
+	case objectIDType:
+		return bson.M{"bsonType": "objectId"}
+	}
+
+	if values, ok := schemaEnums[t]; ok {
+		return bson.M{"bsonType": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return JSONSchemaFor(t.Elem())
+	case reflect.String:
+		return bson.M{"bsonType": "string"}
+	case reflect.Bool:
+		return bson.M{"bsonType": "bool"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return bson.M{"bsonType": bson.A{"int", "long"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return bson.M{"bsonType": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return bson.M{"bsonType": "binData"}
+		}
+		return bson.M{"bsonType": "array", "items": JSONSchemaFor(t.Elem())}
+	case reflect.Map:
+		return bson.M{"bsonType": "object"}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return bson.M{}
+	}
+}
+
+// object schema of a struct, fields without omitempty are required and unknown fields are rejected
+func structSchema(t reflect.Type) bson.M {
+	properties := bson.D{}
+	required := bson.A{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty := bsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		prop := JSONSchemaFor(field.Type)
+
+		// a nil pointer without omitempty is written as null
+		if field.Type.Kind() == reflect.Ptr && !omitEmpty {
+			prop["bsonType"] = bson.A{prop["bsonType"], "null"}
+		}
+
+		properties = append(properties, bson.E{Key: name, Value: prop})
+
+		if !omitEmpty || name == "_id" {
+			required = append(required, name)
+		}
+	}
+
+	schema := bson.M{
+		"bsonType":             "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// bson key of a struct field and whether it's omitted when empty
+func bsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("bson")
+	parts := strings.Split(tag, ",")
+
+	name := parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty
+}
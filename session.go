@@ -3,7 +3,6 @@ package main
 import (
 	"time"
 
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -48,23 +47,31 @@ type Session struct {
 
 // session from account id
 func NewSessionFromAccount(account *Account) *Session {
-	now := time.Now().UTC()
-	exp := time.Now().Add(time.Duration(SECONDS_IN_DAY) * time.Second).UTC()
+	now := Now()
+	exp := now.Add(time.Duration(SECONDS_IN_DAY) * time.Second)
 
 	return &Session{
-		ID:        primitive.NewObjectID(),
+		ID:        NewObjectID(),
 		AccountID: account.ID,
 		Account:   account,
-		SessionID: uuid.NewString(),
+		SessionID: NewUUID(),
 		CreatedAt: &now,
 		UpdatedAt: &now,
 		Expires:   &exp,
 	}
 }
 
+// places a session's login within the last day of the timeline, after the account joined
+func (s *MongoStore) placeSession(session *Session, account *Account) {
+	login := s.timeline.Between(latest(*account.CreatedAt, s.timeline.End.Add(-24*time.Hour)), s.timeline.End)
+	session.CreatedAt = timePtr(login)
+	session.UpdatedAt = timePtr(login)
+	session.Expires = timePtr(login.Add(time.Duration(SECONDS_IN_DAY) * time.Second))
+}
+
 // tells us if the session has expired or not
 func (s *Session) IsExpired() bool {
-	return s.Expires.Before(time.Now().UTC())
+	return s.Expires.Before(Now())
 }
 
 // Persist Sessions
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// persistence backend the generated collections are written to - generators only ever
+// fill the store, sinks are the only place that knows where the documents end up
+type Sink interface {
+	// prepares the target for a fresh seed of the given collections
+	Open(ctx context.Context, collections []string) error
+	// writes a batch of documents to a collection
+	WriteBatch(ctx context.Context, colName string, docs []interface{}) error
+	// creates the indexes of a collection, after all documents have been written
+	CreateIndexes(ctx context.Context, colName string, indexes []IndexSpec) error
+	// flushes and releases anything the sink holds
+	Close(ctx context.Context) error
+}
+
+// fans every call out to several sinks, e.g. mongo plus a file export
+type MultiSink []Sink
+
+func (m MultiSink) Open(ctx context.Context, collections []string) error {
+	for _, sink := range m {
+		if err := sink.Open(ctx, collections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MultiSink) WriteBatch(ctx context.Context, colName string, docs []interface{}) error {
+	for _, sink := range m {
+		if err := sink.WriteBatch(ctx, colName, docs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MultiSink) CreateIndexes(ctx context.Context, colName string, indexes []IndexSpec) error {
+	for _, sink := range m {
+		if err := sink.CreateIndexes(ctx, colName, indexes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closes every sink, even if an earlier one fails
+func (m MultiSink) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// builds the sink(s) described by the config
+func NewSinkFromConfig(cfg *Config) (Sink, error) {
+	sinks := MultiSink{}
+
+	if !cfg.Offline {
+		mongoSink, err := NewMongoSinkFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		mongoSink.Validators = cfg.Validators
+		mongoSink.Safety = cfg.Safety
+		mongoSink.Seed = cfg.Seed
+		mongoSink.Append = cfg.Append
+		sinks = append(sinks, mongoSink)
+	}
+
+	if cfg.ExportDir != "" {
+		sinks = append(sinks, NewFileSink(cfg.ExportDir, cfg.ExportCanonical))
+	}
+
+	// offline without an export is a dry run
+	if len(sinks) == 0 {
+		return NewMemorySink(), nil
+	}
+
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+
+	return sinks, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// writes collections to disk as MongoDB Extended JSON, one document per line (mongoimport compatible)
+type FileSink struct {
+	Dir       string
+	Canonical bool // canonical extended json keeps exact bson types, relaxed is easier to read
+
+	files   map[string]*os.File
+	writers map[string]*bufio.Writer
+	counts  map[string]int
+}
+
+func NewFileSink(dir string, canonical bool) *FileSink {
+	return &FileSink{
+		Dir:       dir,
+		Canonical: canonical,
+		files:     make(map[string]*os.File),
+		writers:   make(map[string]*bufio.Writer),
+		counts:    make(map[string]int),
+	}
+}
+
+// file a collection is exported to
+func (f *FileSink) CollectionPath(colName string) string {
+	return filepath.Join(f.Dir, colName+".json")
+}
+
+// creates the export directory and truncates a file per collection, so every
+// collection has a file even when nothing was generated for it
+func (f *FileSink) Open(ctx context.Context, collections []string) error {
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return fmt.Errorf("creating export directory %s: %w", f.Dir, err)
+	}
+
+	for _, name := range collections {
+		if _, err := f.writer(name); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf(" - Exporting collections to %s\n", f.Dir)
+
+	return nil
+}
+
+// buffered writer of a collection file, created on first use
+func (f *FileSink) writer(colName string) (*bufio.Writer, error) {
+	if w, ok := f.writers[colName]; ok {
+		return w, nil
+	}
+
+	file, err := os.Create(f.CollectionPath(colName))
+	if err != nil {
+		return nil, err
+	}
+
+	f.files[colName] = file
+	f.writers[colName] = bufio.NewWriter(file)
+
+	return f.writers[colName], nil
+}
+
+// appends a batch of documents to the collection file
+func (f *FileSink) WriteBatch(ctx context.Context, colName string, docs []interface{}) error {
+	w, err := f.writer(colName)
+	if err != nil {
+		return err
+	}
+
+	for i, doc := range docs {
+		line, err := bson.MarshalExtJSON(doc, f.Canonical, false)
+		if err != nil {
+			return fmt.Errorf("encoding %s document %d: %w", colName, f.counts[colName]+i, err)
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	f.counts[colName] += len(docs)
+
+	return nil
+}
+
+// files have no indexes, mongoimport users create them separately
+func (f *FileSink) CreateIndexes(ctx context.Context, colName string, indexes []IndexSpec) error {
+	return nil
+}
+
+// flushes and closes every collection file
+func (f *FileSink) Close(ctx context.Context) error {
+	var errs []error
+	for name, w := range f.writers {
+		if err := w.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("flushing %s: %w", name, err))
+		}
+		if err := f.files[name].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	f.files = make(map[string]*os.File)
+	f.writers = make(map[string]*bufio.Writer)
+
+	return errors.Join(errs...)
+}
+
+// decodes every line of an exported collection into out, a pointer to a slice of model pointers
+func (f *FileSink) LoadCollection(ctx context.Context, colName string, out interface{}) error {
+	file, err := os.Open(f.CollectionPath(colName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	slice := reflect.ValueOf(out).Elem()
+	elemType := slice.Type().Elem().Elem()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 32*int(MB))
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		doc := reflect.New(elemType)
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), f.Canonical, doc.Interface()); err != nil {
+			return fmt.Errorf("%s line %d: %w", f.CollectionPath(colName), line, err)
+		}
+		slice.Set(reflect.Append(slice, doc))
+	}
+
+	return scanner.Err()
+}
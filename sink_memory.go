@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// keeps every written document in memory - dry runs and testing generation without a database
+type MemorySink struct {
+	mu      sync.Mutex
+	docs    map[string][]interface{}
+	indexes map[string][]IndexSpec
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{
+		docs:    make(map[string][]interface{}),
+		indexes: make(map[string][]IndexSpec),
+	}
+}
+
+// resets the given collections
+func (m *MemorySink) Open(ctx context.Context, collections []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs = make(map[string][]interface{})
+	m.indexes = make(map[string][]IndexSpec)
+	for _, name := range collections {
+		m.docs[name] = []interface{}{}
+	}
+
+	fmt.Printf(" - Writing to memory, nothing will be persisted\n")
+
+	return nil
+}
+
+func (m *MemorySink) WriteBatch(ctx context.Context, colName string, docs []interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs[colName] = append(m.docs[colName], docs...)
+
+	return nil
+}
+
+// replaces documents with the same _id, appends the ones it doesn't hold yet
+func (m *MemorySink) ReplaceBatch(ctx context.Context, colName string, docs []interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range docs {
+		id, found := docID(doc), false
+		for i, held := range m.docs[colName] {
+			if docID(held) == id {
+				m.docs[colName][i], found = doc, true
+				break
+			}
+		}
+		if !found {
+			m.docs[colName] = append(m.docs[colName], doc)
+		}
+	}
+
+	return nil
+}
+
+// only records the indexes
+func (m *MemorySink) CreateIndexes(ctx context.Context, colName string, indexes []IndexSpec) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.indexes[colName] = append(m.indexes[colName], indexes...)
+	return nil
+}
+
+func (m *MemorySink) Close(ctx context.Context) error {
+	return nil
+}
+
+// every document written to a collection
+func (m *MemorySink) Documents(colName string) []interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.docs[colName]
+}
+
+// every index created on a collection
+func (m *MemorySink) Indexes(colName string) []IndexSpec {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.indexes[colName]
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the documents of a memory sink as a dataset, like LoadDataset reads them from a database
+func memoryDataset(sink *MemorySink) *Dataset {
+	d := &Dataset{}
+	for name, target := range d.targets() {
+		docs := reflect.ValueOf(target).Elem()
+		for _, doc := range sink.Documents(name) {
+			docs.Set(reflect.Append(docs, reflect.ValueOf(doc)))
+		}
+	}
+	return d
+}
+
+func TestGenerateIntoMemoryCounts(t *testing.T) {
+	sink := seedToMemory(t, 7)
+
+	cases := []struct {
+		collection string
+		min, max   int
+	}{
+		{collection: "accounts", min: 21, max: 21}, // 20 random plus the dev admin
+		{collection: "boards", min: len(defaultBoards), max: len(defaultBoards)},
+		{collection: "asset_sources", min: 30, max: 30},
+		{collection: "articles", min: 3, max: 3},
+		{collection: "threads", min: 1, max: 10},
+		{collection: "posts", min: 1, max: 10 * 7},
+		{collection: "article_comments", min: 1, max: 3 * 4},
+		{collection: "sessions", min: 1, max: 21},
+		{collection: "identities", min: 10, max: 10 + 10*7},
+	}
+
+	for _, c := range cases {
+		t.Run(c.collection, func(t *testing.T) {
+			if n := len(sink.Documents(c.collection)); n < c.min || n > c.max {
+				t.Errorf("got %d documents, want %d-%d", n, c.min, c.max)
+			}
+		})
+	}
+}
+
+func TestGenerateIntoMemoryReferences(t *testing.T) {
+	d := memoryDataset(seedToMemory(t, 7))
+
+	report := Verify(d, true)
+	if !report.OK() {
+		for _, issue := range report.Issues {
+			t.Error(issue)
+		}
+	}
+
+	threads := idSet{}
+	for _, thread := range d.Threads {
+		threads[thread.ID] = true
+	}
+	for _, post := range d.Posts {
+		if !threads[post.Thread] {
+			t.Errorf("post %s is in thread %s which wasn't written", post.ID.Hex(), post.Thread.Hex())
+		}
+	}
+}
+
+func TestGenerateIntoMemoryIndexes(t *testing.T) {
+	sink := seedToMemory(t, 7)
+
+	for _, name := range collections {
+		t.Run(name, func(t *testing.T) {
+			got := []string{}
+			for _, spec := range sink.Indexes(name) {
+				got = append(got, spec.Name)
+			}
+			want := []string{}
+			for _, spec := range collectionIndexes[name] {
+				want = append(want, spec.Name)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got indexes %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateIntoMemoryPostNumbers(t *testing.T) {
+	d := memoryDataset(seedToMemory(t, 7))
+
+	numbers := map[primitive.ObjectID]map[int]bool{}
+	for _, post := range d.Posts {
+		if numbers[post.Board] == nil {
+			numbers[post.Board] = map[int]bool{}
+		}
+		numbers[post.Board][post.PostNumber] = true
+	}
+
+	for _, board := range d.Boards {
+		if board.PostRef != len(numbers[board.ID]) {
+			t.Errorf("board %s has post_ref %d for %d posts", board.Short, board.PostRef, len(numbers[board.ID]))
+		}
+		for n := 1; n <= board.PostRef; n++ {
+			if !numbers[board.ID][n] {
+				t.Errorf("board %s has no post number %d", board.Short, n)
+			}
+		}
+	}
+}
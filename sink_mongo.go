@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// writes to a live MongoDB database
+type MongoSink struct {
+	Client *mongo.Client
+	DB     *mongo.Database
+	DBName string
+
+	URI string
+
+	Validators bool         // create collections with a $jsonSchema validator derived from the models
+	Safety     SafetyConfig // what may be dropped on Open
+	Seed       int64        // recorded in the marker document
+	Append     bool         // keep the existing database, Open only creates missing collections
+}
+
+// connects using the MONGO_* environment variables
+func NewMongoSinkFromEnv() (*MongoSink, error) {
+	database := ensureEnvVarVaild(os.Getenv("MONGO_DATABASE"))
+	uri := ensureEnvVarVaild(getMongoDBConnectionString())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MongoDB: %w", err)
+	}
+
+	return &MongoSink{
+		Client: client,
+		DB:     client.Database(database),
+		DBName: database,
+		URI:    uri,
+	}, nil
+}
+
+// drops the database and recreates all collections for a clean slate, only once
+// the safety checks pass
+func (m *MongoSink) Open(ctx context.Context, collections []string) error {
+	fmt.Printf(" - Connected to MongoDB using database: %s\n", m.DBName)
+
+	if m.Append {
+		return m.openAppend(ctx, collections)
+	}
+
+	if err := m.checkDrop(ctx); err != nil {
+		return err
+	}
+
+	fmt.Print(" - Dropping Collections")
+	if err := m.DB.Drop(ctx); err != nil {
+		return fmt.Errorf("dropping database: %w", err)
+	}
+
+	for i, name := range collections {
+		fmt.Print("\033[G\033[K")
+		fmt.Printf(" - Recreating Collections: %v/%v", i+1, len(collections))
+		if err := m.createCollection(ctx, name); err != nil {
+			fmt.Println("Error creating collection:", name, err)
+			continue
+		}
+	}
+
+	return m.writeMarker(ctx, collections)
+}
+
+// keeps everything and only creates the collections that don't exist yet, an empty
+// database is marked as the seeder's own
+func (m *MongoSink) openAppend(ctx context.Context, collections []string) error {
+	if err := m.checkTarget(); err != nil {
+		return err
+	}
+
+	existing, err := m.DB.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return fmt.Errorf("listing collections: %w", err)
+	}
+
+	found := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		found[name] = true
+	}
+
+	for _, name := range collections {
+		if found[name] {
+			continue
+		}
+		fmt.Printf(" - Creating missing collection: %s\n", name)
+		if err := m.createCollection(ctx, name); err != nil {
+			return fmt.Errorf("creating collection %s: %w", name, err)
+		}
+	}
+
+	if len(existing) == 0 {
+		return m.writeMarker(ctx, collections)
+	}
+
+	return nil
+}
+
+// creates a collection, with its validator if enabled
+func (m *MongoSink) createCollection(ctx context.Context, name string) error {
+	opts := options.CreateCollection()
+	if validator := CollectionValidator(name); m.Validators && validator != nil {
+		opts.SetValidator(validator).SetValidationLevel("strict").SetValidationAction("error")
+	}
+	return m.DB.CreateCollection(ctx, name, opts)
+}
+
+// inserts a batch of documents unordered, so one bad document doesn't stop the rest
+func (m *MongoSink) WriteBatch(ctx context.Context, colName string, docs []interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	_, err := m.DB.Collection(colName).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return nil
+	}
+
+	if isDuplicateIDOnly(err) {
+		return &DuplicateIDError{Err: err}
+	}
+
+	if isTransientMongoError(err) {
+		return &TransientError{Err: err}
+	}
+
+	return err
+}
+
+// replaces documents by _id, unordered like inserts
+func (m *MongoSink) ReplaceBatch(ctx context.Context, colName string, docs []interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": docID(doc)}).SetReplacement(doc))
+	}
+
+	_, err := m.DB.Collection(colName).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil && isTransientMongoError(err) {
+		return &TransientError{Err: err}
+	}
+
+	return err
+}
+
+// timeouts, network errors and errors the server labels as retryable
+func isTransientMongoError(err error) bool {
+	if mongo.IsTimeout(err) || mongo.IsNetworkError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var labeled mongo.LabeledError
+	if errors.As(err, &labeled) {
+		return labeled.HasErrorLabel("RetryableWriteError") || labeled.HasErrorLabel("TransientTransactionError")
+	}
+
+	return false
+}
+
+// only _id is indexed while inserting, so a bulk error made up entirely of duplicate _id
+// errors means the documents were written before, by an earlier attempt when retrying
+func isDuplicateIDOnly(err error) bool {
+	var bulk mongo.BulkWriteException
+	if !errors.As(err, &bulk) || bulk.WriteConcernError != nil || len(bulk.WriteErrors) == 0 {
+		return false
+	}
+
+	for _, we := range bulk.WriteErrors {
+		if we.Code != 11000 || !strings.Contains(we.Message, "_id_") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// creates indexes on a collection
+func (m *MongoSink) CreateIndexes(ctx context.Context, colName string, indexes []IndexSpec) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.IndexModel, 0, len(indexes))
+	for _, spec := range indexes {
+		opts := options.Index().SetName(spec.Name)
+		if spec.Unique {
+			opts.SetUnique(true)
+		}
+		if spec.ExpireAfterSeconds != nil {
+			opts.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+		}
+		if spec.Weights != nil {
+			opts.SetWeights(spec.Weights)
+		}
+		if spec.Partial != "" {
+			opts.SetPartialFilterExpression(bson.D{{Key: spec.Partial, Value: bson.D{{Key: "$exists", Value: true}}}})
+		}
+		models = append(models, mongo.IndexModel{Keys: spec.Keys, Options: opts})
+	}
+
+	_, err := m.DB.Collection(colName).Indexes().CreateMany(ctx, models)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("seeded data violates a unique index on %s: %w", colName, err)
+	}
+
+	return err
+}
+
+// disconnects the client
+func (m *MongoSink) Close(ctx context.Context) error {
+	return m.Client.Disconnect(ctx)
+}
+
+// decodes every document of a collection into out, a pointer to a slice of models
+func (m *MongoSink) LoadCollection(ctx context.Context, colName string, out interface{}) error {
+	cursor, err := m.DB.Collection(colName).Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// what a deleted account leaves behind
+const (
+	CascadeAnonymize = "anonymize" // content stays, identities lose the account and authorship goes anonymous
+	CascadeDelete    = "delete"    // content is soft deleted along with the account
+)
+
+// share of documents soft deleted on their own, on top of the ones with a deleted status
+type DeleteConfig struct {
+	Posts    int    `json:"posts"`    // percent
+	Comments int    `json:"comments"` // percent
+	Assets   int    `json:"assets"`   // percent
+	Cascade  string `json:"cascade"`  // anonymize or delete, applied to the content of deleted accounts
+}
+
+// soft deletes a document at a time, an earlier deletion is kept. Deleting counts as an update
+func softDelete(deletedAt, updatedAt **time.Time, at time.Time) {
+	if *deletedAt == nil || at.Before(**deletedAt) {
+		*deletedAt = timePtr(at)
+	}
+	touch(updatedAt, at)
+}
+
+// sets DeletedAt wherever a status says deleted, cascades it to what depends on the deleted
+// document and soft deletes the configured share of posts, comments and assets. Runs once
+// everything is generated, deletions happen after the last activity they affect
+func (s *MongoStore) ApplySoftDeletes() {
+	cfg := s.Config.Deletes
+
+	assets := make(map[primitive.ObjectID]*Asset, len(s.cAssets))
+	for _, a := range s.cAssets {
+		assets[a.ID] = a
+	}
+	deleteAssets := func(ids []primitive.ObjectID, at time.Time) {
+		for _, id := range ids {
+			if a, ok := assets[id]; ok {
+				softDelete(&a.DeletedAt, &a.UpdatedAt, latest(at, *a.CreatedAt))
+			}
+		}
+	}
+
+	identities := make(map[primitive.ObjectID]*Identity, len(s.cIdentites))
+	for _, i := range s.cIdentites {
+		identities[i.ID] = i
+	}
+
+	// latest activity of every account, nobody deletes their account and keeps posting
+	lastActive := make(map[primitive.ObjectID]time.Time)
+	active := func(account primitive.ObjectID, t *time.Time) {
+		if t != nil && t.After(lastActive[account]) {
+			lastActive[account] = *t
+		}
+	}
+	for _, t := range s.cThreads {
+		if i, ok := identities[t.Creator]; ok {
+			active(i.Account, t.CreatedAt)
+		}
+	}
+	for _, p := range s.cPosts {
+		if i, ok := identities[p.Creator]; ok {
+			active(i.Account, p.UpdatedAt)
+		}
+	}
+	for _, c := range s.cArticleComments {
+		active(c.AuthorID, c.UpdatedAt)
+	}
+	for _, a := range s.cAssets {
+		active(a.AccountID, a.CreatedAt)
+	}
+	authorAccounts := make(map[primitive.ObjectID]primitive.ObjectID, len(s.cArticleAuthors))
+	for _, aa := range s.cArticleAuthors {
+		authorAccounts[aa.ID] = aa.AuthorID
+	}
+	for _, a := range s.cArticles {
+		for _, ref := range append([]primitive.ObjectID{a.AuthorID}, a.CoAuthors...) {
+			active(authorAccounts[ref], a.CreatedAt)
+		}
+	}
+
+	deleted := 0
+
+	// accounts, with their sessions and what they made
+	deletedAccounts := make(map[primitive.ObjectID]time.Time)
+	for _, a := range s.cAccounts {
+		if a.Status != AccountStatusDeleted {
+			continue
+		}
+		// loaded accounts may have been deleted by an earlier run
+		if a.DeletedAt == nil {
+			softDelete(&a.DeletedAt, &a.UpdatedAt, s.timeline.After(latest(*a.CreatedAt, *a.UpdatedAt, lastActive[a.ID])))
+			deleted++
+		}
+		deletedAccounts[a.ID] = *a.DeletedAt
+	}
+
+	for _, session := range s.cSessions {
+		at, ok := deletedAccounts[session.AccountID]
+		if !ok {
+			continue
+		}
+		// the last login happened before the deletion
+		login := s.timeline.Between(latest(*session.Account.CreatedAt, at.Add(-24*time.Hour)), at)
+		session.CreatedAt, session.UpdatedAt = timePtr(login), timePtr(login)
+		session.Expires = timePtr(login.Add(time.Duration(SECONDS_IN_DAY) * time.Second))
+		softDelete(&session.DeletedAt, &session.UpdatedAt, at)
+	}
+
+	for _, i := range s.cIdentites {
+		at, ok := deletedAccounts[i.Account]
+		if !ok {
+			continue
+		}
+		if cfg.Cascade == CascadeDelete {
+			i.Status = IdentityStatusDeleted
+			softDelete(&i.DeletedAt, &i.UpdatedAt, at)
+		} else {
+			i.Account = primitive.NilObjectID
+			touch(&i.UpdatedAt, at)
+		}
+	}
+
+	for _, aa := range s.cArticleAuthors {
+		if _, ok := deletedAccounts[aa.AuthorID]; ok {
+			aa.Anonymize = true
+		}
+	}
+
+	// threads stay up, other people's posts are in them
+	if cfg.Cascade == CascadeDelete {
+		for _, p := range s.cPosts {
+			if i, ok := identities[p.Creator]; ok && i.DeletedAt != nil {
+				softDelete(&p.DeletedAt, &p.UpdatedAt, *i.DeletedAt)
+				deleteAssets(p.Assets, *i.DeletedAt)
+			}
+		}
+		for _, c := range s.cArticleComments {
+			if at, ok := deletedAccounts[c.AuthorID]; ok {
+				softDelete(&c.DeletedAt, &c.UpdatedAt, at)
+				deleteAssets(c.Assets, at)
+			}
+		}
+		for _, a := range s.cAssets {
+			if at, ok := deletedAccounts[a.AccountID]; ok {
+				softDelete(&a.DeletedAt, &a.UpdatedAt, latest(at, *a.CreatedAt))
+			}
+		}
+	} else {
+		for _, c := range s.cArticleComments {
+			if at, ok := deletedAccounts[c.AuthorID]; ok {
+				c.AuthorAnon = true
+				touch(&c.UpdatedAt, at)
+			}
+		}
+	}
+
+	// threads, after their last post, taking the posts down with them
+	posts := make(map[primitive.ObjectID]*Post, len(s.cPosts))
+	for _, p := range s.cPosts {
+		posts[p.ID] = p
+	}
+	for _, t := range s.cThreads {
+		if t.Status != ThreadStatusDeleted {
+			continue
+		}
+		if t.DeletedAt == nil {
+			softDelete(&t.DeletedAt, &t.UpdatedAt, s.timeline.After(*t.UpdatedAt))
+			deleted++
+		}
+		at := *t.DeletedAt
+		deleteAssets(t.Assets, at)
+		for _, ref := range t.Posts {
+			if p, ok := posts[ref]; ok {
+				softDelete(&p.DeletedAt, &p.UpdatedAt, latest(at, *p.CreatedAt))
+				deleteAssets(p.Assets, at)
+			}
+		}
+	}
+
+	// articles, after their last comment, taking the comments down with them
+	comments := make(map[primitive.ObjectID]*ArticleComment, len(s.cArticleComments))
+	for _, c := range s.cArticleComments {
+		comments[c.ID] = c
+	}
+	for _, a := range s.cArticles {
+		if a.Status != ArticleStatusDeleted {
+			continue
+		}
+		if a.DeletedAt == nil {
+			softDelete(&a.DeletedAt, &a.UpdatedAt, s.timeline.After(*a.UpdatedAt))
+			deleted++
+		}
+		at := *a.DeletedAt
+		deleteAssets(a.Assets, at)
+		for _, ref := range a.Comments {
+			if c, ok := comments[ref]; ok {
+				softDelete(&c.DeletedAt, &c.UpdatedAt, latest(at, *c.CreatedAt))
+				deleteAssets(c.Assets, at)
+			}
+		}
+	}
+
+	// the configured share of what's left, deleted some time after it was last touched
+	for _, p := range s.cPosts {
+		if p.DeletedAt == nil && RandomIntBetween(0, 100) < cfg.Posts {
+			at := s.timeline.After(*p.UpdatedAt)
+			softDelete(&p.DeletedAt, &p.UpdatedAt, at)
+			deleteAssets(p.Assets, at)
+			deleted++
+		}
+	}
+	for _, c := range s.cArticleComments {
+		if c.DeletedAt == nil && RandomIntBetween(0, 100) < cfg.Comments {
+			at := s.timeline.After(*c.UpdatedAt)
+			softDelete(&c.DeletedAt, &c.UpdatedAt, at)
+			deleteAssets(c.Assets, at)
+			deleted++
+		}
+	}
+	for _, a := range s.cAssets {
+		if a.DeletedAt == nil && RandomIntBetween(0, 100) < cfg.Assets {
+			softDelete(&a.DeletedAt, &a.UpdatedAt, s.timeline.After(*a.UpdatedAt))
+			deleted++
+		}
+	}
+
+	fmt.Printf(" - Soft deleted %d posts, comments, assets, threads, articles and accounts (%s cascade on %d deleted accounts)\n", deleted, cfg.Cascade, len(deletedAccounts))
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// where generated words and sentences come from
+type TextSource interface {
+	Word() string     // lowercase word without punctuation, for tags and headings
+	Sentence() string // ends with a space, sentences are concatenated as they are
+}
+
+// where text comes from, lorem when no corpus is set
+type TextConfig struct {
+	Corpus []string            `json:"corpus"` // text files or directories of .txt files
+	Order  int                 `json:"order"`  // words of context of the markov chain
+	Boards map[string][]string `json:"boards"` // corpus per board short name, replaces the default for the board
+}
+
+// lorem words from words_lorem
+type LoremSource struct{}
+
+func (LoremSource) Word() string {
+	return GetLoremWord()
+}
+
+func (LoremSource) Sentence() string {
+	wc := RandomIntBetween(6, 14)
+	sentence := ""
+	for i := 0; i < wc; i++ {
+		sentence = sentence + GetLoremWord() + " "
+	}
+	return sentence
+}
+
+var (
+	defaultText TextSource = LoremSource{}
+	boardText              = map[string]TextSource{}
+
+	// source used by GetSentence and GetWord right now
+	activeText TextSource = LoremSource{}
+)
+
+// trains the configured sources, lorem stays the default without a corpus
+func LoadTextSources(cfg TextConfig) error {
+	defaultText, boardText = LoremSource{}, map[string]TextSource{}
+
+	if len(cfg.Corpus) > 0 {
+		src, err := TrainMarkov(cfg.Order, cfg.Corpus)
+		if err != nil {
+			return err
+		}
+		defaultText = src
+		fmt.Printf(" - Trained an order %d markov chain on %d sentences of %s\n", cfg.Order, len(src.starts), strings.Join(cfg.Corpus, ", "))
+	}
+
+	shorts := make([]string, 0, len(cfg.Boards))
+	for short := range cfg.Boards {
+		shorts = append(shorts, short)
+	}
+	sort.Strings(shorts)
+
+	for _, short := range shorts {
+		corpus := cfg.Boards[short]
+		src, err := TrainMarkov(cfg.Order, corpus)
+		if err != nil {
+			return fmt.Errorf("board %s: %w", short, err)
+		}
+		boardText[short] = src
+		fmt.Printf(" - Trained an order %d markov chain on %d sentences of %s for /%s/\n", cfg.Order, len(src.starts), strings.Join(corpus, ", "), short)
+	}
+
+	activeText = defaultText
+	return nil
+}
+
+// text of the board is generated from here on, its own source if it has one. An empty short
+// name goes back to the default source
+func UseBoardText(short string) {
+	if src, ok := boardText[short]; ok {
+		activeText = src
+		return
+	}
+	activeText = defaultText
+}
+
+// n-gram markov chain over the words of a corpus, punctuation and case kept
+type MarkovSource struct {
+	order  int
+	chain  map[string][]string // words following an n-gram, repeated as often as they followed it
+	starts [][]string          // opening n-grams of the corpus sentences
+	words  []string            // normalized vocabulary, for Word
+}
+
+// longest sentence generated before it's cut off
+const maxMarkovSentenceWords = 40
+
+// trains a chain of the order on the files, directories are walked for .txt files in
+// lexical order so a seeded run generates the same text every time
+func TrainMarkov(order int, paths []string) (*MarkovSource, error) {
+	m := &MarkovSource{order: order, chain: make(map[string][]string)}
+	seen := map[string]bool{}
+
+	for _, path := range paths {
+		files, err := corpusFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			m.train(strings.Fields(string(raw)), seen)
+		}
+	}
+
+	if len(m.starts) == 0 {
+		return nil, fmt.Errorf("corpus %s has no sentence longer than %d words", strings.Join(paths, ", "), order)
+	}
+	return m, nil
+}
+
+// the file, or the .txt files under the directory
+func corpusFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".txt") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (m *MarkovSource) train(tokens []string, seen map[string]bool) {
+	sentence := []string{}
+	for _, tok := range tokens {
+		// quote links only come from the reply graph, posts would quote posts that don't exist
+		if quoteLinkPattern.MatchString(tok) {
+			continue
+		}
+		sentence = append(sentence, tok)
+
+		if w := normalizeWord(tok); w != "" && !seen[w] {
+			seen[w] = true
+			m.words = append(m.words, w)
+		}
+
+		if endsSentence(tok) {
+			m.addSentence(sentence)
+			sentence = []string{}
+		}
+	}
+	m.addSentence(sentence)
+}
+
+func (m *MarkovSource) addSentence(words []string) {
+	if len(words) <= m.order {
+		return
+	}
+	m.starts = append(m.starts, words[:m.order])
+	for i := m.order; i < len(words); i++ {
+		key := markovKey(words[i-m.order : i])
+		m.chain[key] = append(m.chain[key], words[i])
+	}
+}
+
+func (m *MarkovSource) Word() string {
+	return m.words[RandomIntBetween(0, len(m.words))]
+}
+
+// walks the chain from a sentence opening to its end, short walks are retried a few times
+func (m *MarkovSource) Sentence() string {
+	var words []string
+	for attempt := 0; attempt < 5; attempt++ {
+		words = append([]string{}, m.starts[RandomIntBetween(0, len(m.starts))]...)
+		for len(words) < maxMarkovSentenceWords && !endsSentence(words[len(words)-1]) {
+			next := m.chain[markovKey(words[len(words)-m.order:])]
+			if len(next) == 0 {
+				break
+			}
+			words = append(words, next[RandomIntBetween(0, len(next))])
+		}
+		if len(words) >= 4 {
+			break
+		}
+	}
+	return strings.Join(words, " ") + " "
+}
+
+func markovKey(words []string) string {
+	return strings.Join(words, "\x00")
+}
+
+// word ends with . ! or ?, closing quotes and brackets aside
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, `"')]»”’`)
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+}
+
+// lowercase letters and digits of the word
+func normalizeWord(word string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, word)
+}
@@ -33,11 +33,11 @@ type Thread struct {
 
 // new empty thread ptr
 func NewThread() *Thread {
-	ts := time.Now().UTC()
+	ts := Now()
 	return &Thread{
-		ID:        primitive.NewObjectID(),
+		ID:        NewObjectID(),
 		Status:    GetWeightedThreadStatus(),
-		Title:     GetSentence(),
+		Title:     Unicodify(UnicodeTitles, GetSentence()),
 		Body:      GetParagraphsBetween(1, 4),
 		Slug:      GetSlug(12, 16),
 		Board:     primitive.NilObjectID,
@@ -54,7 +54,7 @@ func NewThread() *Thread {
 
 // Randomize thread values
 func (t *Thread) Randomize(boardId, creatorId primitive.ObjectID) {
-	ts := time.Now().UTC()
+	ts := Now()
 	t.Board = boardId
 	t.Creator = creatorId
 	t.Mods = []primitive.ObjectID{creatorId}
@@ -63,30 +63,42 @@ func (t *Thread) Randomize(boardId, creatorId primitive.ObjectID) {
 
 // Generate Threads
 func (s *MongoStore) GenerateThreads(min, max int) {
-	threadCount := RandomIntBetween(min, max)
+	if len(s.cAccounts) == 0 {
+		fmt.Println(" - Skipped Threads, no account to create them")
+		return
+	}
+
+	plan := s.PlanThreadBoards(RandomIntBetween(min, max))
 
-	for i := 0; i < threadCount; i++ {
+	for i, threadBoard := range plan {
 		fmt.Print("\033[G\033[K")
-		fmt.Printf(" - Generating Threads: %v/%v", i+1, threadCount)
+		fmt.Printf(" - Generating Threads: %v/%v", i+1, len(plan))
 
+		UseBoardText(threadBoard.Short)
 		thread := NewThread()
-		threadBoard := s.GetRandomBoard()
+		thread.Tags = s.WithBoardTags(threadBoard, thread.Tags)
 		threadCreatorAccount := s.GetRandomAccount()
 		threadCreatorIdentity := NewIdentity(threadCreatorAccount.ID, thread.ID, ThreadRoleCreator)
 
+		// after both the board and the creator's account exist
+		created := s.timeline.After(latest(*threadBoard.CreatedAt, *threadCreatorAccount.CreatedAt))
+		thread.CreatedAt, thread.UpdatedAt = timePtr(created), timePtr(created)
+		threadCreatorIdentity.CreatedAt, threadCreatorIdentity.UpdatedAt = timePtr(created), timePtr(created)
+
 		thread.Board = threadBoard.ID
+		thread.Flags = s.GetWeightedThreadFlags(thread, threadBoard)
 		thread.Creator = threadCreatorIdentity.ID
 		threadCreatorIdentity.Thread = thread.ID
 
-		mediaCt := RandomIntBetween(0, 9)
-		pmedIds, err := s.GenerateAssetCount(mediaCt, threadCreatorAccount.ID)
+		mediaCt := s.RandomBoardMediaCount(threadBoard)
+		pmedIds, err := s.GenerateAssetCount(mediaCt, threadCreatorAccount.ID, created, s.Config.Board(threadBoard.Short).AssetTypes)
 		if err != nil {
-			fmt.Println(err)
-			continue
+			s.reportUploads(err)
 		}
 
 		thread.Assets = pmedIds
 
+		touch(&threadBoard.UpdatedAt, created)
 		threadBoard.Threads = append(threadBoard.Threads, thread.ID)
 		s.cUserThreadIdentitys[thread.ID] = make(map[primitive.ObjectID]*Identity)
 		s.cUserThreadIdentitys[thread.ID][threadCreatorAccount.ID] = threadCreatorIdentity
@@ -94,7 +106,9 @@ func (s *MongoStore) GenerateThreads(min, max int) {
 		s.cThreads = append(s.cThreads, thread)
 	}
 
+	UseBoardText("")
 	fmt.Print("\n")
+	s.PickStickies()
 }
 
 // Thread Mod list contains id?
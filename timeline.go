@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/binary"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// span of simulated history every generated timestamp falls into. It ends at the epoch on seeded
+// runs and at the wall clock otherwise, documents are placed so that nothing predates what it
+// depends on (a post its thread, a thread its board and creator) and updates never precede creation
+type Timeline struct {
+	Start time.Time
+	End   time.Time
+}
+
+func NewTimeline(cfg *Config) *Timeline {
+	end := time.Now().UTC()
+	if cfg.Seed != 0 {
+		end, _ = cfg.SeedEpoch()
+	}
+	end = end.UTC().Truncate(time.Millisecond)
+
+	return &Timeline{
+		Start: end.Add(-cfg.Window.Duration),
+		End:   end,
+	}
+}
+
+// random time in [from, to], from if the range is empty. Millisecond precision, like bson dates
+func (t *Timeline) Between(from, to time.Time) time.Time {
+	from = from.Truncate(time.Millisecond)
+	to = to.Truncate(time.Millisecond)
+	if !to.After(from) {
+		return from
+	}
+	span := to.Sub(from).Milliseconds()
+	return from.Add(time.Duration(rng.Int63n(span+1)) * time.Millisecond)
+}
+
+// random time from the later of from and the start of the timeline to its end
+func (t *Timeline) After(from time.Time) time.Time {
+	return t.Between(latest(from, t.Start), t.End)
+}
+
+// n strictly increasing times after from up to the end, e.g. the posts of a thread. Fewer when
+// there aren't n milliseconds left before the end
+func (t *Timeline) Sequence(from time.Time, n int) []time.Time {
+	from = latest(from, t.Start).Truncate(time.Millisecond)
+	if room := int(t.End.Sub(from).Milliseconds()); n > room {
+		n = room
+	}
+	if n < 0 {
+		n = 0
+	}
+
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = t.Between(from.Add(time.Millisecond), t.End)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	prev := from
+	for i := range times {
+		if !times[i].After(prev) {
+			times[i] = prev.Add(time.Millisecond)
+		}
+		prev = times[i]
+	}
+
+	// pushed past the end by ties close to it, moved back below the next one
+	next := t.End.Add(time.Millisecond)
+	for i := len(times) - 1; i >= 0; i-- {
+		if !times[i].Before(next) {
+			times[i] = next.Add(-time.Millisecond)
+		}
+		next = times[i]
+	}
+
+	return times
+}
+
+// id with the timestamp of t, the rest of it keeps the id unique
+func objectIDAt(id primitive.ObjectID, t time.Time) primitive.ObjectID {
+	binary.BigEndian.PutUint32(id[0:4], uint32(t.Unix()))
+	return id
+}
+
+// gives every generated document an ObjectID whose timestamp is its created_at, ids are drawn
+// before documents are placed on the timeline. References to the documents follow, loaded
+// documents keep their ids
+func (s *MongoStore) StampObjectIDs() {
+	d := s.Dataset()
+	ids := make(map[primitive.ObjectID]primitive.ObjectID)
+
+	for _, target := range d.targets() {
+		docs := reflect.ValueOf(target).Elem()
+		for i := 0; i < docs.Len(); i++ {
+			doc := docs.Index(i).Elem()
+			id, _ := doc.FieldByName("ID").Interface().(primitive.ObjectID)
+			if _, ok := s.loaded[id]; ok {
+				continue
+			}
+			created := doc.FieldByName("CreatedAt")
+			if !created.IsValid() || created.IsNil() {
+				continue
+			}
+			ids[id] = objectIDAt(id, *created.Interface().(*time.Time))
+		}
+	}
+
+	for _, target := range d.targets() {
+		remapObjectIDs(reflect.ValueOf(target), ids)
+	}
+}
+
+// replaces every ObjectID reachable from v that ids has a new one for. A document reached twice,
+// like the account of a session, already holds its new id the second time
+func remapObjectIDs(v reflect.Value, ids map[primitive.ObjectID]primitive.ObjectID) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			remapObjectIDs(v.Elem(), ids)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				remapObjectIDs(v.Field(i), ids)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			remapObjectIDs(v.Index(i), ids)
+		}
+	case reflect.Array:
+		if v.Type() != objectIDType || !v.CanSet() {
+			return
+		}
+		if id, ok := ids[v.Interface().(primitive.ObjectID)]; ok {
+			v.Set(reflect.ValueOf(id))
+		}
+	}
+}
+
+// the later of the times
+func latest(times ...time.Time) time.Time {
+	var max time.Time
+	for _, t := range times {
+		if t.After(max) {
+			max = t
+		}
+	}
+	return max
+}
+
+// pointer to a copy of t, documents never share a timestamp
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// moves a document's updated_at forward to t, if t is later
+func touch(updatedAt **time.Time, t time.Time) {
+	if *updatedAt == nil || t.After(**updatedAt) {
+		*updatedAt = timePtr(t)
+	}
+}
+
+// accounts ordered by join date, rebuilt whenever accounts were added
+func (s *MongoStore) accountsByJoin() []*Account {
+	if len(s.cJoinOrder) != len(s.cAccounts) {
+		s.cJoinOrder = append([]*Account{}, s.cAccounts...)
+		sort.SliceStable(s.cJoinOrder, func(i, j int) bool {
+			return s.cJoinOrder[i].CreatedAt.Before(*s.cJoinOrder[j].CreatedAt)
+		})
+	}
+	return s.cJoinOrder
+}
+
+// random account that had already joined at t, nil if none had
+func (s *MongoStore) GetRandomAccountJoinedBefore(t time.Time) *Account {
+	joined := s.accountsByJoin()
+	n := sort.Search(len(joined), func(i int) bool { return joined[i].CreatedAt.After(t) })
+	if n == 0 {
+		return nil
+	}
+	return joined[RandomIntBetween(0, n)]
+}
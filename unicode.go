@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// fields the unicode profile mixes into
+const (
+	UnicodeUsernames  = "usernames"
+	UnicodeIdentities = "identities"
+	UnicodeTitles     = "titles"
+	UnicodeBodies     = "bodies"
+	UnicodeTags       = "tags"
+	UnicodeFileNames  = "file_names"
+)
+
+// character classes of the unicode profile
+const (
+	UnicodeEmoji     = "emoji"
+	UnicodeCJK       = "cjk"
+	UnicodeRTL       = "rtl"
+	UnicodeCombining = "combining"
+	UnicodeZeroWidth = "zero_width"
+	UnicodeLong      = "long"
+)
+
+var unicodeFields = []string{UnicodeUsernames, UnicodeIdentities, UnicodeTitles, UnicodeBodies, UnicodeTags, UnicodeFileNames}
+var unicodeClasses = []string{UnicodeEmoji, UnicodeCJK, UnicodeRTL, UnicodeCombining, UnicodeZeroWidth, UnicodeLong}
+
+// content profile mixing non ascii text into generated values. Rates are percentages of
+// the values of a field that get a sample of one of the classes, body rates are per paragraph
+type UnicodeConfig struct {
+	Rate    int            `json:"rate"`    // every field without a rate of its own
+	Fields  map[string]int `json:"fields"`  // rate by field
+	Classes []string       `json:"classes"` // classes mixed in, all of them when empty
+}
+
+// unicode settings of the run, set from the config before anything is generated
+var unicodeConfig = UnicodeConfig{}
+
+func SetUnicodeConfig(c UnicodeConfig) {
+	unicodeConfig = c
+}
+
+func (c UnicodeConfig) rate(field string) int {
+	if rate, ok := c.Fields[field]; ok {
+		return rate
+	}
+	return c.Rate
+}
+
+var (
+	unicodeEmoji = []string{
+		"😀", "😂", "🥲", "🤔", "🔥", "👍", "👍🏽", "🎉", "💀", "🐸", "🇯🇵", "🇸🇪",
+		"👨‍👩‍👧‍👦", "🧑🏿‍💻", "🏳️‍🌈", "❤️‍🔥", "👩🏻‍🚀", "🫠", "☕️", "✅",
+	}
+	unicodeCJK = []string{
+		"你好世界", "测试数据", "漢字", "日本語のテキスト", "こんにちは", "カタカナ", "ひらがな",
+		"한국어", "안녕하세요", "𠜎𠜱𠝹", "中文字符串", "東京",
+	}
+	unicodeRTL = []string{
+		"مرحبا بالعالم", "اختبار", "العربية", "שלום עולם", "בדיקה", "עברית", "فارسی",
+		"\u200fשלום\u200f", "\u202bمرحبا\u202c", // with direction marks and embeddings
+	}
+	// grave, acute, circumflex, tilde, diaeresis, ring, cedilla, long stroke, double breve, double inverted breve, enclosing circle
+	unicodeCombining = []rune{'\u0300', '\u0301', '\u0302', '\u0303', '\u0308', '\u030a', '\u0327', '\u0336', '\u035c', '\u0361', '\u20dd'}
+	// zero width space, non-joiner, joiner, no-break space (bom) and word joiner
+	unicodeZeroWidth = []string{"\u200b", "\u200c", "\u200d", "\ufeff", "\u2060"}
+)
+
+// s with a sample of a configured class mixed in, at the field's rate
+func Unicodify(field, s string) string {
+	// no draw at all when off, seeded runs without the profile stay as they were
+	rate := unicodeConfig.rate(field)
+	if rate <= 0 || !chance(rate) {
+		return s
+	}
+
+	classes := unicodeConfig.Classes
+	if len(classes) == 0 {
+		classes = unicodeClasses
+	}
+
+	switch classes[RandomIntBetween(0, len(classes))] {
+	case UnicodeEmoji:
+		return insertWord(s, unicodeEmoji[RandomIntBetween(0, len(unicodeEmoji))])
+	case UnicodeCJK:
+		return insertWord(s, unicodeCJK[RandomIntBetween(0, len(unicodeCJK))])
+	case UnicodeRTL:
+		return insertWord(s, unicodeRTL[RandomIntBetween(0, len(unicodeRTL))])
+	case UnicodeCombining:
+		return addCombiningMarks(s)
+	case UnicodeZeroWidth:
+		return insertRunes(s, unicodeZeroWidth[RandomIntBetween(0, len(unicodeZeroWidth))])
+	case UnicodeLong:
+		return insertWord(s, GetLongUnbrokenString())
+	}
+	return s
+}
+
+// word put between two words of s, or glued on when s is a single word
+func insertWord(s, word string) string {
+	words := strings.Fields(s)
+	if len(words) < 2 {
+		if RandomIntBetween(0, 2) == 0 {
+			return word + s
+		}
+		return s + word
+	}
+
+	at := RandomIntBetween(0, len(words)+1)
+	words = append(words[:at], append([]string{word}, words[at:]...)...)
+
+	joined := strings.Join(words, " ")
+	// sentences keep their trailing space
+	if strings.HasSuffix(s, " ") {
+		joined += " "
+	}
+	return joined
+}
+
+// inserts the invisible runes inside s, between two of its runes
+func insertRunes(s, insert string) string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return s + insert
+	}
+	at := RandomIntBetween(1, len(runes))
+	return string(runes[:at]) + insert + string(runes[at:])
+}
+
+// stacks combining marks on a few letters of s
+func addCombiningMarks(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		sb.WriteRune(r)
+		if r != ' ' && chance(20) {
+			for n := RandomIntBetween(1, 4); n > 0; n-- {
+				sb.WriteRune(unicodeCombining[RandomIntBetween(0, len(unicodeCombining))])
+			}
+		}
+	}
+	if sb.Len() == len(s) && s != "" {
+		// nothing was marked, mark the first rune
+		_, size := utf8.DecodeRuneInString(s)
+		return s[:size] + string(unicodeCombining[RandomIntBetween(0, len(unicodeCombining))]) + s[size:]
+	}
+	return sb.String()
+}
+
+// 80 to 300 letters without a break opportunity, for wrapping and overflow
+func GetLongUnbrokenString() string {
+	return RandomLettersBetween(80, 301)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// namespaces of the registry
+const (
+	UniqueUsername = "username"
+	UniqueEmail    = "email"
+)
+
+// hands out values that were never handed out before, per namespace. Values are compared case
+// insensitively, a collision is redrawn a few times and then made unique with a numeric suffix
+type UniqueRegistry struct {
+	Retries int // redraws before suffixing
+
+	taken      map[string]map[string]bool
+	collisions map[string]*CollisionStats
+}
+
+// how often a namespace collided and how the collisions were resolved
+type CollisionStats struct {
+	Collisions int // values drawn that were taken
+	Redrawn    int // resolved by drawing again
+	Suffixed   int // resolved by a suffix
+}
+
+func NewUniqueRegistry(retries int) *UniqueRegistry {
+	return &UniqueRegistry{
+		Retries:    retries,
+		taken:      make(map[string]map[string]bool),
+		collisions: make(map[string]*CollisionStats),
+	}
+}
+
+// claims a value, false if it's taken already
+func (r *UniqueRegistry) Reserve(namespace, value string) bool {
+	taken, ok := r.taken[namespace]
+	if !ok {
+		taken = make(map[string]bool)
+		r.taken[namespace] = taken
+	}
+
+	key := strings.ToLower(value)
+	if taken[key] {
+		return false
+	}
+	taken[key] = true
+	return true
+}
+
+// claims the first free value drawn from draw, after Retries collisions the last draw gets
+// the lowest free suffix appended
+func (r *UniqueRegistry) Unique(namespace string, draw func() string) string {
+	stats := r.stats(namespace)
+
+	value := draw()
+	for attempt := 0; ; attempt++ {
+		if r.Reserve(namespace, value) {
+			if attempt > 0 {
+				stats.Redrawn++
+			}
+			return value
+		}
+
+		stats.Collisions++
+		if attempt >= r.Retries {
+			break
+		}
+		value = draw()
+	}
+
+	for n := 2; ; n++ {
+		suffixed := withSuffix(namespace, value, n)
+		if r.Reserve(namespace, suffixed) {
+			stats.Suffixed++
+			return suffixed
+		}
+	}
+}
+
+func (r *UniqueRegistry) stats(namespace string) *CollisionStats {
+	stats, ok := r.collisions[namespace]
+	if !ok {
+		stats = &CollisionStats{}
+		r.collisions[namespace] = stats
+	}
+	return stats
+}
+
+// prints the collisions of every namespace that had any
+func (r *UniqueRegistry) Print() {
+	namespaces := make([]string, 0, len(r.collisions))
+	for ns := range r.collisions {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		stats := r.collisions[ns]
+		if stats.Collisions == 0 {
+			continue
+		}
+		fmt.Printf(" - %s collisions: %d taken values drawn, %d resolved by redrawing, %d by a suffix\n", ns, stats.Collisions, stats.Redrawn, stats.Suffixed)
+	}
+}
+
+// value made unique with n, emails keep their domain
+func withSuffix(namespace, value string, n int) string {
+	if namespace == UniqueEmail {
+		if at := strings.LastIndex(value, "@"); at >= 0 {
+			return value[:at] + strconv.Itoa(n) + value[at:]
+		}
+	}
+	return value + strconv.Itoa(n)
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestUniqueRegistry(t *testing.T) {
+	cases := []struct {
+		name      string
+		namespace string
+		taken     []string
+		draws     []string // drawn in order, the last one repeats
+		retries   int
+		want      string
+		stats     CollisionStats
+	}{
+		{name: "free", namespace: UniqueUsername, draws: []string{"anon"}, retries: 2, want: "anon"},
+		{name: "redrawn", namespace: UniqueUsername, taken: []string{"anon"}, draws: []string{"anon", "anon", "lurker"}, retries: 2,
+			want: "lurker", stats: CollisionStats{Collisions: 2, Redrawn: 1}},
+		{name: "case insensitive", namespace: UniqueUsername, taken: []string{"Anon"}, draws: []string{"anon", "lurker"}, retries: 2,
+			want: "lurker", stats: CollisionStats{Collisions: 1, Redrawn: 1}},
+		{name: "suffixed", namespace: UniqueUsername, taken: []string{"anon"}, draws: []string{"anon"}, retries: 2,
+			want: "anon2", stats: CollisionStats{Collisions: 3, Suffixed: 1}},
+		{name: "lowest free suffix", namespace: UniqueUsername, taken: []string{"anon", "anon2", "anon3"}, draws: []string{"anon"},
+			want: "anon4", stats: CollisionStats{Collisions: 1, Suffixed: 1}},
+		{name: "email keeps its domain", namespace: UniqueEmail, taken: []string{"anon@example.test"}, draws: []string{"anon@example.test"},
+			want: "anon2@example.test", stats: CollisionStats{Collisions: 1, Suffixed: 1}},
+		{name: "namespaces are apart", namespace: UniqueEmail, draws: []string{"anon"}, want: "anon"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := NewUniqueRegistry(c.retries)
+			// taken in the other namespace only
+			other := UniqueEmail
+			if c.namespace == UniqueEmail {
+				other = UniqueUsername
+			}
+			r.Reserve(other, "anon")
+			for _, value := range c.taken {
+				r.Reserve(c.namespace, value)
+			}
+
+			i := 0
+			got := r.Unique(c.namespace, func() string {
+				draw := c.draws[len(c.draws)-1]
+				if i < len(c.draws) {
+					draw = c.draws[i]
+				}
+				i++
+				return draw
+			})
+
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+			if stats := *r.stats(c.namespace); stats != c.stats {
+				t.Errorf("got %+v, want %+v", stats, c.stats)
+			}
+			if r.Reserve(c.namespace, got) {
+				t.Errorf("%q wasn't reserved", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,595 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// every collection of a seeded database, loaded into the models
+type Dataset struct {
+	Accounts        []*Account
+	Boards          []*Board
+	Threads         []*Thread
+	Posts           []*Post
+	Articles        []*Article
+	ArticleComments []*ArticleComment
+	ArticleAuthors  []*ArticleAuthor
+	Identities      []*Identity
+	AssetSources    []*AssetSource
+	Assets          []*Asset
+	Sessions        []*Session
+}
+
+// anything that can decode a whole collection into a pointer to a slice of models
+type CollectionLoader interface {
+	LoadCollection(ctx context.Context, colName string, out interface{}) error
+}
+
+// the collection each dataset field is loaded from
+func (d *Dataset) targets() map[string]interface{} {
+	return map[string]interface{}{
+		"accounts":         &d.Accounts,
+		"boards":           &d.Boards,
+		"threads":          &d.Threads,
+		"posts":            &d.Posts,
+		"articles":         &d.Articles,
+		"article_comments": &d.ArticleComments,
+		"article_authors":  &d.ArticleAuthors,
+		"identities":       &d.Identities,
+		"asset_sources":    &d.AssetSources,
+		"assets":           &d.Assets,
+		"sessions":         &d.Sessions,
+	}
+}
+
+// loads every collection through the loader
+func LoadDataset(ctx context.Context, loader CollectionLoader) (*Dataset, error) {
+	d := &Dataset{}
+	targets := d.targets()
+
+	for _, name := range collections {
+		fmt.Print("\033[G\033[K")
+		fmt.Printf(" - Loading %s", name)
+		if err := loader.LoadCollection(ctx, name, targets[name]); err != nil {
+			return nil, fmt.Errorf("loading %s: %w", name, err)
+		}
+	}
+	fmt.Print("\n")
+
+	return d, nil
+}
+
+// the generated (in memory) documents as a dataset
+func (s *MongoStore) Dataset() *Dataset {
+	d := &Dataset{
+		Accounts:        s.cAccounts,
+		Boards:          s.cBoards,
+		Threads:         s.cThreads,
+		Posts:           s.cPosts,
+		Articles:        s.cArticles,
+		ArticleComments: s.cArticleComments,
+		ArticleAuthors:  s.cArticleAuthors,
+		Identities:      s.cIdentites,
+		Assets:          s.cAssets,
+		Sessions:        s.cSessions,
+	}
+	for i := 0; i < len(s.cAssetSrcMap); i++ {
+		d.AssetSources = append(d.AssetSources, s.cAssetSrcMap[i])
+	}
+	return d
+}
+
+type IssueKind string
+
+const (
+	IssueDangling IssueKind = "dangling" // a reference to a document that doesn't exist
+	IssueOrphan   IssueKind = "orphan"   // a document nothing refers to, or not listed by its parent
+	IssueMismatch IssueKind = "mismatch" // references or counters that disagree with each other
+	IssueTime     IssueKind = "time"     // timestamps out of order, e.g. a post older than its thread
+)
+
+// a single integrity problem
+type Issue struct {
+	Kind       IssueKind
+	Collection string
+	ID         primitive.ObjectID
+	Field      string
+	Ref        primitive.ObjectID
+	Detail     string
+}
+
+func (i Issue) String() string {
+	str := fmt.Sprintf("%-8s %s %s", i.Kind, i.Collection, i.ID.Hex())
+	if i.Field != "" {
+		str += " ." + i.Field
+	}
+	if !i.Ref.IsZero() {
+		str += " -> " + i.Ref.Hex()
+	}
+	if i.Detail != "" {
+		str += " (" + i.Detail + ")"
+	}
+	return str
+}
+
+// result of a verification run
+type VerifyReport struct {
+	Checked  map[string]int // documents checked per collection
+	Issues   []Issue
+	Warnings []Issue // generation policy the data doesn't follow, issues when strict
+	Strict   bool
+}
+
+func (r *VerifyReport) add(kind IssueKind, col string, id primitive.ObjectID, field string, ref primitive.ObjectID, detail string) {
+	r.Issues = append(r.Issues, Issue{Kind: kind, Collection: col, ID: id, Field: field, Ref: ref, Detail: detail})
+}
+
+// a check of how the seeder generates data rather than of references, schema or indexes. Data
+// written by something else may break it, so it only warns unless strict
+func (r *VerifyReport) policy(kind IssueKind, col string, id primitive.ObjectID, field string, ref primitive.ObjectID, detail string) {
+	if r.Strict {
+		r.add(kind, col, id, field, ref, detail)
+		return
+	}
+	r.Warnings = append(r.Warnings, Issue{Kind: kind, Collection: col, ID: id, Field: field, Ref: ref, Detail: detail})
+}
+
+func (r *VerifyReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// prints a summary per kind & collection plus the first limit issues
+func (r *VerifyReport) Print(limit int) {
+	hrPrint("Verification Report")
+
+	names := make([]string, 0, len(r.Checked))
+	for name := range r.Checked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf(" - Checked %d %s documents\n", r.Checked[name], name)
+	}
+
+	if len(r.Warnings) > 0 {
+		fmt.Printf("\n - Found %d warnings, -strict makes them issues\n", len(r.Warnings))
+		printIssues(r.Warnings, limit)
+	}
+
+	if r.OK() {
+		fmt.Printf("\n - No issues found\n")
+		return
+	}
+
+	fmt.Printf("\n - Found %d issues\n", len(r.Issues))
+	printIssues(r.Issues, limit)
+}
+
+// prints a count per kind & collection plus the first limit issues
+func printIssues(issues []Issue, limit int) {
+	counts := map[string]int{}
+	for _, issue := range issues {
+		counts[string(issue.Kind)+" "+issue.Collection+"."+issue.Field]++
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Printf("   %6d %s\n", counts[k], k)
+	}
+
+	fmt.Print("\n")
+	for i, issue := range issues {
+		if i == limit {
+			fmt.Printf("   ... %d more\n", len(issues)-limit)
+			break
+		}
+		fmt.Println("  ", issue)
+	}
+}
+
+type idSet map[primitive.ObjectID]bool
+
+// checks every foreign reference in the dataset, and how it was generated. Strict fails on the
+// generation policy checks instead of warning about them
+func Verify(d *Dataset, strict bool) *VerifyReport {
+	r := &VerifyReport{Strict: strict, Checked: map[string]int{
+		"accounts":         len(d.Accounts),
+		"boards":           len(d.Boards),
+		"threads":          len(d.Threads),
+		"posts":            len(d.Posts),
+		"articles":         len(d.Articles),
+		"article_comments": len(d.ArticleComments),
+		"article_authors":  len(d.ArticleAuthors),
+		"identities":       len(d.Identities),
+		"asset_sources":    len(d.AssetSources),
+		"assets":           len(d.Assets),
+		"sessions":         len(d.Sessions),
+	}}
+
+	accounts := idSet{}
+	for _, a := range d.Accounts {
+		accounts[a.ID] = true
+	}
+	boards := map[primitive.ObjectID]*Board{}
+	for _, b := range d.Boards {
+		boards[b.ID] = b
+	}
+	threads := map[primitive.ObjectID]*Thread{}
+	for _, t := range d.Threads {
+		threads[t.ID] = t
+	}
+	posts := map[primitive.ObjectID]*Post{}
+	for _, p := range d.Posts {
+		posts[p.ID] = p
+	}
+	identities := map[primitive.ObjectID]*Identity{}
+	for _, i := range d.Identities {
+		identities[i.ID] = i
+	}
+	articleAuthors := idSet{}
+	for _, a := range d.ArticleAuthors {
+		articleAuthors[a.ID] = true
+	}
+	comments := map[primitive.ObjectID]*ArticleComment{}
+	for _, c := range d.ArticleComments {
+		comments[c.ID] = c
+	}
+	sources := idSet{}
+	for _, s := range d.AssetSources {
+		sources[s.ID] = true
+	}
+	assets := idSet{}
+	for _, a := range d.Assets {
+		assets[a.ID] = true
+	}
+
+	// what is referenced by a parent, to find orphans afterwards
+	listedThreads, listedPosts, usedIdentities := idSet{}, idSet{}, idSet{}
+	listedComments, usedAuthors, usedAssets := idSet{}, idSet{}, idSet{}
+
+	checkAssets := func(col string, id primitive.ObjectID, refs []primitive.ObjectID) {
+		for _, ref := range refs {
+			usedAssets[ref] = true
+			if !assets[ref] {
+				r.add(IssueDangling, col, id, "assets", ref, "")
+			}
+		}
+	}
+
+	for _, b := range d.Boards {
+		for _, ref := range b.Threads {
+			listedThreads[ref] = true
+			t, ok := threads[ref]
+			if !ok {
+				r.add(IssueDangling, "boards", b.ID, "threads", ref, "")
+			} else if t.Board != b.ID {
+				r.add(IssueMismatch, "boards", b.ID, "threads", ref, "thread belongs to another board")
+			}
+		}
+	}
+
+	for _, t := range d.Threads {
+		if _, ok := boards[t.Board]; !ok {
+			r.add(IssueDangling, "threads", t.ID, "board", t.Board, "")
+		}
+		if !listedThreads[t.ID] {
+			r.add(IssueOrphan, "threads", t.ID, "board", t.Board, "not listed in its board's threads")
+		}
+
+		usedIdentities[t.Creator] = true
+		if creator, ok := identities[t.Creator]; !ok {
+			r.add(IssueDangling, "threads", t.ID, "creator", t.Creator, "")
+		} else if creator.Thread != t.ID {
+			r.add(IssueMismatch, "threads", t.ID, "creator", t.Creator, "identity belongs to another thread")
+		}
+
+		for _, ref := range t.Posts {
+			listedPosts[ref] = true
+			p, ok := posts[ref]
+			if !ok {
+				r.add(IssueDangling, "threads", t.ID, "posts", ref, "")
+			} else if p.Thread != t.ID {
+				r.add(IssueMismatch, "threads", t.ID, "posts", ref, "post belongs to another thread")
+			}
+		}
+
+		for _, ref := range t.Mods {
+			usedIdentities[ref] = true
+			if mod, ok := identities[ref]; !ok {
+				r.add(IssueDangling, "threads", t.ID, "mods", ref, "")
+			} else if mod.Thread != t.ID {
+				r.add(IssueMismatch, "threads", t.ID, "mods", ref, "identity belongs to another thread")
+			}
+		}
+
+		checkAssets("threads", t.ID, t.Assets)
+
+		if t.HasFlag(ThreadFlagSticky) && t.Status != ThreadStatusOpen {
+			r.policy(IssueMismatch, "threads", t.ID, "flags", primitive.NilObjectID, "sticky thread isn't open")
+		}
+		if t.Status == ThreadStatusDeleted && !t.HasFlag(ThreadFlagHidden) {
+			r.policy(IssueMismatch, "threads", t.ID, "flags", primitive.NilObjectID, "deleted thread isn't hidden")
+		}
+	}
+
+	maxPostNumber := map[primitive.ObjectID]int{}
+	for _, p := range d.Posts {
+		thread, ok := threads[p.Thread]
+		if !ok {
+			r.add(IssueDangling, "posts", p.ID, "thread", p.Thread, "")
+		} else if thread.Board != p.Board {
+			r.add(IssueMismatch, "posts", p.ID, "board", p.Board, "post and thread are on different boards")
+		}
+		if _, ok := boards[p.Board]; !ok {
+			r.add(IssueDangling, "posts", p.ID, "board", p.Board, "")
+		}
+		if !listedPosts[p.ID] {
+			r.add(IssueOrphan, "posts", p.ID, "thread", p.Thread, "not listed in its thread's posts")
+		}
+
+		usedIdentities[p.Creator] = true
+		if creator, ok := identities[p.Creator]; !ok {
+			r.add(IssueDangling, "posts", p.ID, "creator", p.Creator, "")
+		} else if creator.Thread != p.Thread {
+			r.add(IssueMismatch, "posts", p.ID, "creator", p.Creator, "identity belongs to another thread")
+		}
+
+		checkAssets("posts", p.ID, p.Assets)
+
+		if p.PostNumber > maxPostNumber[p.Board] {
+			maxPostNumber[p.Board] = p.PostNumber
+		}
+	}
+
+	for _, b := range d.Boards {
+		if b.PostRef < maxPostNumber[b.ID] {
+			r.add(IssueMismatch, "boards", b.ID, "post_ref", primitive.NilObjectID,
+				fmt.Sprintf("post_ref %d is behind the highest post number %d", b.PostRef, maxPostNumber[b.ID]))
+		}
+	}
+
+	// quote links, by board short name and post number
+	numbered := map[string]map[int]*Post{}
+	for _, p := range d.Posts {
+		if b, ok := boards[p.Board]; ok {
+			if numbered[b.Short] == nil {
+				numbered[b.Short] = map[int]*Post{}
+			}
+			numbered[b.Short][p.PostNumber] = p
+		}
+	}
+	for _, p := range d.Posts {
+		b, ok := boards[p.Board]
+		if !ok {
+			continue
+		}
+		for _, q := range ParseQuoteLinks(p.Body, b.Short) {
+			target, ok := numbered[q.Board][q.Number]
+			if !ok {
+				r.add(IssueDangling, "posts", p.ID, "body", primitive.NilObjectID, fmt.Sprintf("quotes post %d of /%s/ which doesn't exist", q.Number, q.Board))
+			} else if p.CreatedAt != nil && target.CreatedAt != nil && p.CreatedAt.Before(*target.CreatedAt) {
+				r.policy(IssueTime, "posts", p.ID, "body", target.ID, "quotes a later post")
+			}
+		}
+	}
+
+	for _, i := range d.Identities {
+		// anonymized identities no longer point at an account
+		if !i.Account.IsZero() && !accounts[i.Account] {
+			r.add(IssueDangling, "identities", i.ID, "account", i.Account, "")
+		}
+		if _, ok := threads[i.Thread]; !ok {
+			r.add(IssueDangling, "identities", i.ID, "thread", i.Thread, "")
+		}
+		if !usedIdentities[i.ID] {
+			r.add(IssueOrphan, "identities", i.ID, "", primitive.NilObjectID, "not used by any thread or post")
+		}
+	}
+
+	for _, a := range d.Articles {
+		usedAuthors[a.AuthorID] = true
+		if !articleAuthors[a.AuthorID] {
+			r.add(IssueDangling, "articles", a.ID, "author", a.AuthorID, "")
+		}
+		for _, ref := range a.CoAuthors {
+			usedAuthors[ref] = true
+			if !articleAuthors[ref] {
+				r.add(IssueDangling, "articles", a.ID, "co_authors", ref, "")
+			}
+		}
+
+		maxComment := 0
+		for _, ref := range a.Comments {
+			listedComments[ref] = true
+			c, ok := comments[ref]
+			if !ok {
+				r.add(IssueDangling, "articles", a.ID, "comments", ref, "")
+				continue
+			}
+			if c.CommentNumber > maxComment {
+				maxComment = c.CommentNumber
+			}
+		}
+		if a.CommentRef < maxComment {
+			r.add(IssueMismatch, "articles", a.ID, "comment_ref", primitive.NilObjectID,
+				fmt.Sprintf("comment_ref %d is behind the highest comment number %d", a.CommentRef, maxComment))
+		}
+
+		checkAssets("articles", a.ID, a.Assets)
+	}
+
+	for _, a := range d.ArticleAuthors {
+		if !accounts[a.AuthorID] {
+			r.add(IssueDangling, "article_authors", a.ID, "author", a.AuthorID, "")
+		}
+		if !usedAuthors[a.ID] {
+			r.add(IssueOrphan, "article_authors", a.ID, "", primitive.NilObjectID, "not an author of any article")
+		}
+	}
+
+	for _, c := range d.ArticleComments {
+		if !accounts[c.AuthorID] {
+			r.add(IssueDangling, "article_comments", c.ID, "author", c.AuthorID, "")
+		}
+		if !listedComments[c.ID] {
+			r.add(IssueOrphan, "article_comments", c.ID, "", primitive.NilObjectID, "not listed in any article")
+		}
+		checkAssets("article_comments", c.ID, c.Assets)
+	}
+
+	for _, s := range d.AssetSources {
+		uploaders := map[primitive.ObjectID]bool{}
+		for _, ref := range s.Uploaders {
+			if !accounts[ref] {
+				r.add(IssueDangling, "asset_sources", s.ID, "uploaders", ref, "")
+			}
+			if uploaders[ref] {
+				r.add(IssueMismatch, "asset_sources", s.ID, "uploaders", ref, "listed more than once")
+			}
+			uploaders[ref] = true
+		}
+		if s.Details != nil && (s.AssetType == AssetTypeVideo) != (s.Details.Video != nil) {
+			r.policy(IssueMismatch, "asset_sources", s.ID, "details.video", primitive.NilObjectID, "video metadata on a "+s.AssetType.String()+" or missing on a video")
+		}
+	}
+
+	for _, a := range d.Assets {
+		if !sources[a.SourceID] {
+			r.add(IssueDangling, "assets", a.ID, "source_id", a.SourceID, "")
+		}
+		if !accounts[a.AccountID] {
+			r.add(IssueDangling, "assets", a.ID, "account_id", a.AccountID, "")
+		}
+		if !usedAssets[a.ID] {
+			r.add(IssueOrphan, "assets", a.ID, "", primitive.NilObjectID, "not attached to anything")
+		}
+	}
+
+	for _, s := range d.Sessions {
+		if !accounts[s.AccountID] {
+			r.add(IssueDangling, "sessions", s.ID, "account_id", s.AccountID, "")
+		}
+	}
+
+	verifyTimeline(d, r)
+
+	return r
+}
+
+// checks documents never predate what they depend on and are never updated before they were created,
+// all of it generation policy
+func verifyTimeline(d *Dataset, r *VerifyReport) {
+	before := func(a, b *time.Time) bool {
+		return a != nil && b != nil && a.Before(*b)
+	}
+	updated := func(col string, id primitive.ObjectID, created, updated *time.Time) {
+		if before(updated, created) {
+			r.policy(IssueTime, col, id, "updated_at", primitive.NilObjectID, "updated before it was created")
+		}
+	}
+	deleted := func(col string, id primitive.ObjectID, created, deletedAt *time.Time, deletedStatus bool) {
+		if before(deletedAt, created) {
+			r.policy(IssueTime, col, id, "deleted_at", primitive.NilObjectID, "deleted before it was created")
+		}
+		if deletedStatus && deletedAt == nil {
+			r.policy(IssueMismatch, col, id, "deleted_at", primitive.NilObjectID, "deleted status without deleted_at")
+		}
+	}
+
+	joined := map[primitive.ObjectID]*time.Time{}
+	for _, a := range d.Accounts {
+		joined[a.ID] = a.CreatedAt
+		updated("accounts", a.ID, a.CreatedAt, a.UpdatedAt)
+		deleted("accounts", a.ID, a.CreatedAt, a.DeletedAt, a.Status == AccountStatusDeleted)
+	}
+	boards := map[primitive.ObjectID]*Board{}
+	for _, b := range d.Boards {
+		boards[b.ID] = b
+		updated("boards", b.ID, b.CreatedAt, b.UpdatedAt)
+	}
+	identities := map[primitive.ObjectID]*Identity{}
+	for _, i := range d.Identities {
+		identities[i.ID] = i
+		updated("identities", i.ID, i.CreatedAt, i.UpdatedAt)
+		deleted("identities", i.ID, i.CreatedAt, i.DeletedAt, i.Status == IdentityStatusDeleted)
+	}
+	posts := map[primitive.ObjectID]*Post{}
+	for _, p := range d.Posts {
+		posts[p.ID] = p
+		updated("posts", p.ID, p.CreatedAt, p.UpdatedAt)
+		deleted("posts", p.ID, p.CreatedAt, p.DeletedAt, false)
+		if i, ok := identities[p.Creator]; ok && !i.Account.IsZero() && before(p.CreatedAt, joined[i.Account]) {
+			r.policy(IssueTime, "posts", p.ID, "created_at", i.Account, "posted before the account joined")
+		}
+	}
+
+	for _, t := range d.Threads {
+		updated("threads", t.ID, t.CreatedAt, t.UpdatedAt)
+		deleted("threads", t.ID, t.CreatedAt, t.DeletedAt, t.Status == ThreadStatusDeleted)
+		if b, ok := boards[t.Board]; ok && before(t.CreatedAt, b.CreatedAt) {
+			r.policy(IssueTime, "threads", t.ID, "created_at", t.Board, "created before its board")
+		}
+		if i, ok := identities[t.Creator]; ok && !i.Account.IsZero() && before(t.CreatedAt, joined[i.Account]) {
+			r.policy(IssueTime, "threads", t.ID, "created_at", i.Account, "created before the creator's account joined")
+		}
+
+		// posts are listed in the order they were made
+		prev := t.CreatedAt
+		for _, ref := range t.Posts {
+			p, ok := posts[ref]
+			if !ok {
+				continue
+			}
+			if p.CreatedAt != nil && prev != nil && !p.CreatedAt.After(*prev) {
+				r.policy(IssueTime, "posts", p.ID, "created_at", t.ID, "not after its thread and the previous post")
+			}
+			prev = p.CreatedAt
+		}
+	}
+
+	comments := map[primitive.ObjectID]*ArticleComment{}
+	for _, c := range d.ArticleComments {
+		comments[c.ID] = c
+		updated("article_comments", c.ID, c.CreatedAt, c.UpdatedAt)
+		deleted("article_comments", c.ID, c.CreatedAt, c.DeletedAt, false)
+	}
+	for _, a := range d.Articles {
+		updated("articles", a.ID, a.CreatedAt, a.UpdatedAt)
+		deleted("articles", a.ID, a.CreatedAt, a.DeletedAt, a.Status == ArticleStatusDeleted)
+
+		prev := a.CreatedAt
+		for _, ref := range a.Comments {
+			c, ok := comments[ref]
+			if !ok {
+				continue
+			}
+			if c.CreatedAt != nil && prev != nil && !c.CreatedAt.After(*prev) {
+				r.policy(IssueTime, "article_comments", c.ID, "created_at", a.ID, "not after its article and the previous comment")
+			}
+			prev = c.CreatedAt
+		}
+	}
+
+	for _, s := range d.AssetSources {
+		updated("asset_sources", s.ID, s.CreatedAt, s.UpdatedAt)
+	}
+	for _, a := range d.Assets {
+		updated("assets", a.ID, a.CreatedAt, a.UpdatedAt)
+		deleted("assets", a.ID, a.CreatedAt, a.DeletedAt, false)
+	}
+	for _, s := range d.Sessions {
+		updated("sessions", s.ID, s.CreatedAt, s.UpdatedAt)
+		deleted("sessions", s.ID, s.CreatedAt, s.DeletedAt, false)
+		if before(s.CreatedAt, joined[s.AccountID]) {
+			r.policy(IssueTime, "sessions", s.ID, "created_at", s.AccountID, "logged in before the account joined")
+		}
+	}
+}
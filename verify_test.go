@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestVerify(t *testing.T) {
+	missing := primitive.NewObjectID()
+
+	cases := []struct {
+		name   string
+		mutate func(d *Dataset)
+		strict bool
+		kind   IssueKind // expected issue, none when empty
+		col    string
+		field  string
+		warns  bool // expected a warning instead
+	}{
+		{name: "generated", mutate: func(d *Dataset) {}},
+		{name: "dangling thread of a post", mutate: func(d *Dataset) { d.Posts[0].Thread = missing },
+			kind: IssueDangling, col: "posts", field: "thread"},
+		{name: "dangling asset", mutate: func(d *Dataset) { d.Threads[0].Assets = append(d.Threads[0].Assets, missing) },
+			kind: IssueDangling, col: "threads", field: "assets"},
+		{name: "dangling session account", mutate: func(d *Dataset) { d.Sessions[0].AccountID = missing },
+			kind: IssueDangling, col: "sessions", field: "account_id"},
+		{name: "orphan post", mutate: func(d *Dataset) {
+			thread := d.Threads[0]
+			thread.Posts = thread.Posts[:0]
+		}, kind: IssueOrphan, col: "posts", field: "thread"},
+		{name: "board counter behind", mutate: func(d *Dataset) { d.Boards[0].PostRef = -1 },
+			kind: IssueMismatch, col: "boards", field: "post_ref"},
+		{name: "policy warns", mutate: func(d *Dataset) {
+			d.Accounts[0].UpdatedAt = timePtr(d.Accounts[0].CreatedAt.Add(-time.Hour))
+		}, warns: true},
+		{name: "policy fails when strict", mutate: func(d *Dataset) {
+			d.Accounts[0].UpdatedAt = timePtr(d.Accounts[0].CreatedAt.Add(-time.Hour))
+		}, strict: true, kind: IssueTime, col: "accounts", field: "updated_at"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := memoryDataset(seedToMemory(t, 11))
+			c.mutate(d)
+			report := Verify(d, c.strict)
+
+			if c.warns != (len(report.Warnings) > 0) {
+				t.Errorf("got warnings %v", report.Warnings)
+			}
+			if c.kind == "" {
+				if !report.OK() {
+					t.Errorf("got issues %v", report.Issues)
+				}
+				return
+			}
+			for _, issue := range report.Issues {
+				if issue.Kind == c.kind && issue.Collection == c.col && issue.Field == c.field {
+					return
+				}
+			}
+			t.Errorf("no %s issue on %s.%s in %v", c.kind, c.col, c.field, report.Issues)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// video base url, videos are never fetched so it only has to look like one
+var videoBaseUrl = "https://example.com/videos/"
+
+// video sizes, 16:9
+var videoSourceSizes = []string{
+	"640/360",
+	"854/480",
+	"1280/720",
+	"1920/1080",
+}
+
+// poster frame sizes, index aligned with videoSourceSizes
+var videoPosterSizes = []string{
+	"320/180",
+	"320/180",
+	"320/180",
+	"480/270",
+}
+
+var videoFrameRates = []float64{23.976, 24, 25, 29.97, 30, 50, 60}
+
+// largest video upload, long clips are cut to fit
+var maxVideoFileSize int = int(MB) * 100
+
+// codecs a container extension of videoFileExtensions is found with
+type videoFormat struct {
+	Extension  string
+	Container  string
+	VideoCodec string
+	AudioCodec string
+	BitsPerPx  float64 // video bits per pixel and frame
+}
+
+var videoFormats = []videoFormat{
+	{"mp4", "mp4", "h264", "aac", 0.1},
+	{"mp4", "mp4", "hevc", "aac", 0.06},
+	{"mp4", "mp4", "av1", "opus", 0.05},
+	{"webm", "webm", "vp9", "opus", 0.06},
+	{"webm", "webm", "vp8", "vorbis", 0.12},
+	{"webm", "webm", "av1", "opus", 0.05},
+	{"ogg", "ogg", "theora", "vorbis", 0.15},
+	{"avi", "avi", "mpeg4", "mp3", 0.15},
+	{"mov", "quicktime", "h264", "aac", 0.1},
+	{"mov", "quicktime", "prores", "pcm_s16le", 1.5},
+}
+
+// audio bitrate of every codec but pcm, bits per second
+const audioBitrate = 128000
+
+// 16 bit stereo at 48khz
+const pcmBitrate = 48000 * 16 * 2
+
+// formats of the extension
+func videoFormatsOf(ext string) []videoFormat {
+	formats := []videoFormat{}
+	for _, f := range videoFormats {
+		if f.Extension == ext {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// weighted duration in seconds, mostly short clips
+func GetWeightedVideoDuration() float64 {
+	num := RandomIntBetween(0, 100)
+	if num < 60 {
+		return float64(RandomIntBetween(2000, 30000)) / 1000
+	} else if num < 90 {
+		return float64(RandomIntBetween(30000, 180000)) / 1000
+	} else {
+		return float64(RandomIntBetween(180000, 900000)) / 1000
+	}
+}
+
+// metadata of a video in one of the formats of an extension of exts. The bitrate follows from
+// the size, frame rate and codec, and long videos are cut to maxVideoFileSize
+func GetVideoMeta(width, height int, exts []string) *VideoMeta {
+	formats := videoFormatsOf(exts[RandomIntBetween(0, len(exts))])
+	f := formats[RandomIntBetween(0, len(formats))]
+	fps := videoFrameRates[RandomIntBetween(0, len(videoFrameRates))]
+
+	meta := &VideoMeta{
+		Container:  f.Container,
+		VideoCodec: f.VideoCodec,
+		FrameRate:  fps,
+	}
+
+	bitrate := float64(width*height) * fps * f.BitsPerPx
+	// some clips are silent
+	if chance(80) {
+		meta.AudioCodec = f.AudioCodec
+		if f.AudioCodec == "pcm_s16le" {
+			bitrate += pcmBitrate
+		} else {
+			bitrate += audioBitrate
+		}
+	}
+	meta.Bitrate = uint32(bitrate)
+
+	duration := GetWeightedVideoDuration()
+	if limit := float64(maxVideoFileSize) * 8 / bitrate; duration > limit {
+		duration = limit
+	}
+	// whole frames, in milliseconds
+	frames := math.Floor(duration * fps)
+	if frames < 1 {
+		frames = 1
+	}
+	meta.Duration = math.Round(frames/fps*1000) / 1000
+	meta.PosterAt = math.Round(meta.Duration*float64(RandomIntBetween(0, 50))/100*1000) / 1000
+
+	return meta
+}
+
+// extension of the container of the video
+func (m *VideoMeta) Extension() string {
+	for _, f := range videoFormats {
+		if f.Container == m.Container {
+			return f.Extension
+		}
+	}
+	return ""
+}
+
+// bytes of the whole video at its bitrate
+func (m *VideoMeta) Size() int {
+	return int(float64(m.Bitrate) * m.Duration / 8)
+}
+
+// width and height of a "w/h" size
+func parseSize(size string) (int, int) {
+	sizes := strings.Split(size, "/")
+	width, _ := strconv.Atoi(sizes[0])
+	height, _ := strconv.Atoi(sizes[1])
+	return width, height
+}
+
+// generate a video asset source, with a poster frame as its avatar. The upload mode only picks
+// containers it can write
+func GenerateVideoSource(index int) *AssetSource {
+	ix := RandomIntBetween(0, len(videoSourceSizes))
+	width, height := parseSize(videoSourceSizes[ix])
+	p_width, p_height := parseSize(videoPosterSizes[ix])
+
+	exts := videoFileExtensions
+	if uploadConfig.Dir != "" {
+		exts = containerFileExtensions
+	}
+	meta := GetVideoMeta(width, height, exts)
+	ext := meta.Extension()
+
+	ts := Now()
+	tsn := ts.UnixNano()
+
+	sourceURL := fmt.Sprintf("%s%d.%s", videoBaseUrl, tsn, ext)
+	posterURL := GetImageUrl(index, videoPosterSizes[ix])
+
+	cs_md5, cs_sha256 := GetChecksumFromStr(sourceURL)
+	pcs_md5, pcs_sha256 := GetChecksumFromStr(posterURL)
+
+	sourceFileCtx := &FileCtx{
+		ServerFileName: fmt.Sprintf("%d", tsn),
+		Height:         uint16(height),
+		Width:          uint16(width),
+		FileSize:       uint32(meta.Size()),
+		URL:            sourceURL,
+		Extension:      ext,
+		HashMD5:        cs_md5,
+		HashSHA256:     cs_sha256,
+	}
+
+	posterFileCtx := &FileCtx{
+		ServerFileName: fmt.Sprintf("a-%d", tsn),
+		Height:         uint16(p_height),
+		Width:          uint16(p_width),
+		FileSize:       uint32(GetRandomFileSize() / avatarFileSizeMultiplier),
+		URL:            posterURL,
+		Extension:      "jpg",
+		HashMD5:        pcs_md5,
+		HashSHA256:     pcs_sha256,
+	}
+
+	return &AssetSource{
+		ID: NewObjectID(),
+		Details: &AssetSourceDetails{
+			Source: sourceFileCtx,
+			Avatar: posterFileCtx,
+			Video:  meta,
+		},
+		AssetType: AssetTypeVideo,
+		Uploaders: []primitive.ObjectID{},
+		CreatedAt: &ts,
+		UpdatedAt: &ts,
+	}
+}